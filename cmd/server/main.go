@@ -2,8 +2,16 @@ package main
 
 import (
 	"banner-service/internal/config"
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
 	"banner-service/internal/models/banner"
 	"banner-service/internal/models/banner/dbbanner"
+	"banner-service/internal/models/impression"
+	"banner-service/internal/models/impression/dbimpression"
+	"banner-service/internal/models/job"
+	"banner-service/internal/models/job/dbjob"
+	"banner-service/internal/models/stats"
+	"banner-service/internal/models/stats/dbstats"
 	"banner-service/internal/models/user"
 	"banner-service/internal/models/user/dbuser"
 	"banner-service/pkg/db/postgresql"
@@ -31,8 +39,73 @@ func main() {
 	// Инициализируем и присваиваем обработчик пользователей из пакета user
 	cfg.UserHandler = user.NewHandler(dbuser.NewUserRepository(clientPostgreSQL, logger), logger)
 
+	// Инициализируем хранилище фоновых задач
+	jobRepository := dbjob.NewJobRepository(clientPostgreSQL, logger)
+	cfg.JobHandler = job.NewHandler(jobRepository, logger)
+
 	// Инициализируем и присваиваем обработчик баннеров из пакета banner
-	cfg.BannerHandler = banner.NewHandler(dbbanner.NewBannerRepository(clientPostgreSQL, logger), logger, banner.NewBannerCache(5*time.Minute))
+	bannerRepository := dbbanner.NewBannerRepository(clientPostgreSQL, logger)
+	cfg.BannerHandler = banner.NewHandler(bannerRepository, logger, banner.NewBannerCache(5*time.Minute))
+	cfg.BannerHandler.Jobs = jobRepository
+
+	// Показы баннеров пишутся в базу данных пачками в фоне
+	impressionRepository := dbimpression.NewImpressionRepository(clientPostgreSQL, logger)
+	cfg.Impressions = impression.NewTracker(impressionRepository, logger, 10000, 500, time.Second)
+	cfg.BannerHandler.Impressions = cfg.Impressions
+	cfg.BannerHandler.Clicks = impressionRepository
+
+	// Статистика строится по почасовым агрегатам, которые обновляются в фоне
+	statsRepository := dbstats.NewStatsRepository(clientPostgreSQL, logger)
+	cfg.StatsHandler = stats.NewHandler(statsRepository, logger)
+	cfg.StatsRollup = stats.NewAggregator(statsRepository, logger, time.Minute, 5*time.Minute, cfg.StatsLookback)
+
+	// Счетчики лимита показов по умолчанию хранятся в памяти; при нескольких
+	// экземплярах сервиса нужно общее хранилище
+	switch cfg.FrequencyStore {
+	case "postgres":
+		frequencyCounters := dbbanner.NewFrequencyRepository(clientPostgreSQL, logger)
+		cfg.BannerHandler.Frequency = frequencyCounters
+		// Истекшие счетчики в общем хранилище удаляет отдельная фоновая задача
+		cfg.FrequencyCleaner = banner.NewFrequencyCleaner(frequencyCounters, logger, cfg.FrequencyCleanupInterval)
+	case "memory":
+		frequencyStore := banner.NewMemoryFrequencyStore()
+		defer frequencyStore.Close()
+		cfg.BannerHandler.Frequency = frequencyStore
+	default:
+		logger.Fatalf("unknown frequency_store %q", cfg.FrequencyStore)
+	}
+
+	// Язык баннера выбирается из поддерживаемых, остальные служат запасными
+	cfg.BannerHandler.Locales = cfg.Locales
+
+	// Ссылки перехода по баннерам подписываются секретом из конфигурации и строятся
+	// от внешнего адреса сервиса, а не от заголовка Host запроса
+	if cfg.TrackingSecret != "" {
+		cfg.BannerHandler.Links = banner.NewLinkSigner(cfg.TrackingSecret, cfg.TrackingBaseURL, cfg.TrackingLinkTTL)
+	} else {
+		logger.Warn("tracking_secret is not set, click tracking is disabled")
+	}
+
+	// Регистрируем фоновые задачи
+	cfg.JobRunner = job.NewRunner(jobRepository, logger, 5*time.Second)
+	cfg.JobRunner.Register(banner.JobTypeDeleteBanners, banner.NewBulkDeleteTask(bannerRepository, cfg.BannerHandler.Cache, logger))
+
+	// Удаленные баннеры хранятся в корзине trash_retention, затем удаляются окончательно
+	cfg.TrashPurger = banner.NewPurger(bannerRepository, logger, time.Hour, cfg.TrashRetention)
+
+	// Инициализируем обработчики справочников тегов и фич
+	cfg.TagHandler = banner.NewTagHandler(dbbanner.NewTagRepository(clientPostgreSQL, logger), logger)
+	cfg.FeatureHandler = banner.NewFeatureHandler(dbbanner.NewFeatureRepository(clientPostgreSQL, logger), logger)
+	cfg.TagHandler.Cache = cfg.BannerHandler.Cache
+	cfg.FeatureHandler.Cache = cfg.BannerHandler.Cache
+
+	// Шаблоны нужны и справочнику, и созданию баннеров из шаблона
+	templateRepository := dbbanner.NewTemplateRepository(clientPostgreSQL, logger)
+	cfg.TemplateHandler = banner.NewTemplateHandler(templateRepository, logger)
+	cfg.BannerHandler.Templates = templateRepository
+
+	// Журнал изменений пишут сами репозитории, здесь только его просмотр
+	cfg.AuditHandler = audit.NewHandler(dbaudit.NewAuditRepository(clientPostgreSQL, logger), logger)
 
 	// Запускаем приложение
 	err = cfg.Start(logger)
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"banner-service/internal/models/audit"
 	"banner-service/internal/models/banner"
+	"banner-service/internal/models/impression"
+	"banner-service/internal/models/job"
+	"banner-service/internal/models/stats"
 	"banner-service/internal/models/user"
 	"banner-service/pkg/logging"
 	"context"
@@ -18,11 +22,31 @@ import (
 )
 
 type Config struct {
-	Port          string        `yaml:"port" env:"PORT" env-default:"8080"`
-	IsDebug       *bool         `yaml:"is_debug" env:"IS_DEBUG" env-default:"false"`
-	Storage       StorageConfig `yaml:"storage"`
-	UserHandler   *user.Handler
-	BannerHandler *banner.Handler
+	Port                     string        `yaml:"port" env:"PORT" env-default:"8080"`
+	IsDebug                  *bool         `yaml:"is_debug" env:"IS_DEBUG" env-default:"false"`
+	Storage                  StorageConfig `yaml:"storage"`
+	TrackingSecret           string        `yaml:"tracking_secret" env:"TRACKING_SECRET"`
+	TrackingBaseURL          string        `yaml:"tracking_base_url" env:"TRACKING_BASE_URL"`
+	TrackingLinkTTL          time.Duration `yaml:"tracking_link_ttl" env:"TRACKING_LINK_TTL" env-default:"24h"`
+	FrequencyStore           string        `yaml:"frequency_store" env:"FREQUENCY_STORE" env-default:"memory"`
+	FrequencyCleanupInterval time.Duration `yaml:"frequency_cleanup_interval" env:"FREQUENCY_CLEANUP_INTERVAL" env-default:"10m"`
+	Locales                  []string      `yaml:"locales" env:"LOCALES" env-default:"ru,en"`
+	TrashRetention           time.Duration `yaml:"trash_retention" env:"TRASH_RETENTION" env-default:"720h"`
+	StatsLookback            time.Duration `yaml:"stats_lookback" env:"STATS_LOOKBACK" env-default:"6h"`
+	UserHandler              *user.Handler
+	BannerHandler            *banner.Handler
+	TagHandler               *banner.TagHandler
+	FeatureHandler           *banner.FeatureHandler
+	TemplateHandler          *banner.TemplateHandler
+	JobHandler               *job.Handler
+	JobRunner                *job.Runner
+	Impressions              *impression.Tracker
+	StatsHandler             *stats.Handler
+	StatsRollup              *stats.Aggregator
+	TrashPurger              *banner.Purger
+	// FrequencyCleaner задан только для общего хранилища счетчиков лимита показов
+	FrequencyCleaner *banner.FrequencyCleaner
+	AuditHandler     *audit.Handler
 }
 
 type StorageConfig struct {
@@ -73,6 +97,15 @@ func (c *Config) Start(logger *logging.Logger) error {
 	shutdownChan := make(chan os.Signal, 1)
 	signal.Notify(shutdownChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Запускаем обработку фоновых задач
+	c.JobRunner.Start()
+	c.Impressions.Start()
+	c.StatsRollup.Start()
+	c.TrashPurger.Start()
+	if c.FrequencyCleaner != nil {
+		c.FrequencyCleaner.Start()
+	}
+
 	// Запускаем сервер в горутине
 	go func() {
 		if err := server.Serve(listener); err != http.ErrServerClosed {
@@ -93,6 +126,14 @@ func (c *Config) Start(logger *logging.Logger) error {
 
 	// Закрываем ресурсы приложения
 	logger.Info("closing application resources...")
+	// Сервер уже не принимает запросы, поэтому все показы находятся в очереди трекера
+	c.Impressions.Stop()
+	c.StatsRollup.Stop()
+	c.TrashPurger.Stop()
+	if c.FrequencyCleaner != nil {
+		c.FrequencyCleaner.Stop()
+	}
+	c.JobRunner.Stop()
 	c.CloseCache()
 
 	logger.Info("application stopped")
@@ -109,20 +150,71 @@ func (c *Config) CloseCache() {
 func (c *Config) ConfigureRouter() chi.Router {
 	router := chi.NewRouter()
 
-	router.Use(middleware.Logger)
+	router.Use(middleware.RequestID, middleware.RealIP, middleware.Logger, audit.Middleware)
 
-	router.Post("/register", c.UserHandler.Register)
+	// Токен для регистрации и удаления пользователя не обязателен; если он есть, изменение запишется в журнал от его имени
+	router.Post("/register", userMiddleware(c.UserHandler.Register))
 	router.Post("/login", c.UserHandler.Login)
 	router.Get("/users", c.UserHandler.GetUsers)
 	router.Get("/user/{id}", c.UserHandler.GetUserByID)
-	router.Delete("/delete/{id}", c.UserHandler.DeleteUser)
+	router.Delete("/delete/{id}", userMiddleware(c.UserHandler.DeleteUser))
 
-	router.Get("/user_banner", func(w http.ResponseWriter, r *http.Request) {
+	router.Get("/user_banner", userMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		c.BannerHandler.GetUserBanner(w, r, &banner.RealAdminChecker{}) // Здесь мы передаем fakeAdminChecker
-	})
+	}))
+	router.Post("/user_banners", userMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		c.BannerHandler.GetUserBanners(w, r, &banner.RealAdminChecker{})
+	}))
+	router.Get("/banner/{id}/click", userMiddleware(c.BannerHandler.ClickBanner))
+	router.Get("/banner", jwtMiddleware(c.BannerHandler.GetBanners))
+	router.Get("/banner/trash", jwtMiddleware(c.BannerHandler.GetTrash))
+	router.Get("/banners/export", jwtMiddleware(c.BannerHandler.ExportBanners))
+	router.Post("/banners/import", jwtMiddleware(c.BannerHandler.ImportBanners))
+	// Устаревший маршрут, оставлен для совместимости с GET /banner
 	router.Get("/banner/{feature_id}/{tag_id}/{limit}/{offset}", jwtMiddleware(c.BannerHandler.GetBannerFilter))
 	router.Post("/banner", jwtMiddleware(c.BannerHandler.CreateBannerHandler))
+	router.Delete("/banner", jwtMiddleware(c.BannerHandler.BulkDeleteBanners))
 	router.Put("/banner/{id}", jwtMiddleware(c.BannerHandler.UpdateBannerHandler))
+	router.Patch("/banner/{id}", jwtMiddleware(c.BannerHandler.PatchBannerHandler))
+	router.Get("/banner/{id}/stats", jwtMiddleware(c.StatsHandler.GetBannerStats))
+	router.Get("/banner/{id}/variants", jwtMiddleware(c.BannerHandler.GetVariants))
+	router.Post("/banner/{id}/variants", jwtMiddleware(c.BannerHandler.CreateVariant))
+	router.Put("/banner/{id}/variants/{variant_id}", jwtMiddleware(c.BannerHandler.UpdateVariant))
+	router.Delete("/banner/{id}/variants/{variant_id}", jwtMiddleware(c.BannerHandler.DeleteVariant))
+	router.Get("/banner/{id}/translations", jwtMiddleware(c.BannerHandler.GetTranslations))
+	router.Put("/banner/{id}/translations/{locale}", jwtMiddleware(c.BannerHandler.PutTranslation))
+	router.Delete("/banner/{id}/translations/{locale}", jwtMiddleware(c.BannerHandler.DeleteTranslation))
+	router.Get("/banner/{id}/versions", jwtMiddleware(c.BannerHandler.GetBannerVersions))
+	router.Post("/banner/{id}/versions/{version}/restore", jwtMiddleware(c.BannerHandler.RestoreBannerVersion))
 	router.Delete("/delete-banner/{id}", jwtMiddleware(c.BannerHandler.DeleteBannerHandler))
+	router.Post("/banner/{id}/restore", jwtMiddleware(c.BannerHandler.RestoreBannerHandler))
+	for _, transition := range []string{"submit", "approve", "reject", "publish", "archive", "unarchive"} {
+		router.Post("/banner/{id}/"+transition, jwtMiddleware(c.BannerHandler.Transition(transition)))
+	}
+
+	router.Get("/tags", jwtMiddleware(c.TagHandler.GetTags))
+	router.Post("/tags", jwtMiddleware(c.TagHandler.CreateTag))
+	router.Get("/tags/{id}", jwtMiddleware(c.TagHandler.GetTag))
+	router.Put("/tags/{id}", jwtMiddleware(c.TagHandler.UpdateTag))
+	router.Delete("/tags/{id}", jwtMiddleware(c.TagHandler.DeleteTag))
+
+	router.Get("/features", jwtMiddleware(c.FeatureHandler.GetFeatures))
+	router.Post("/features", jwtMiddleware(c.FeatureHandler.CreateFeature))
+	router.Get("/features/{id}", jwtMiddleware(c.FeatureHandler.GetFeature))
+	router.Put("/features/{id}", jwtMiddleware(c.FeatureHandler.UpdateFeature))
+	router.Delete("/features/{id}", jwtMiddleware(c.FeatureHandler.DeleteFeature))
+
+	router.Get("/templates", jwtMiddleware(c.TemplateHandler.GetTemplates))
+	router.Post("/templates", jwtMiddleware(c.TemplateHandler.CreateTemplate))
+	router.Get("/templates/{id}", jwtMiddleware(c.TemplateHandler.GetTemplate))
+	router.Put("/templates/{id}", jwtMiddleware(c.TemplateHandler.UpdateTemplate))
+	router.Delete("/templates/{id}", jwtMiddleware(c.TemplateHandler.DeleteTemplate))
+	router.Get("/templates/{id}/versions", jwtMiddleware(c.TemplateHandler.GetTemplateVersions))
+
+	router.Get("/jobs/{id}", jwtMiddleware(c.JobHandler.GetJob))
+
+	router.Get("/stats", jwtMiddleware(c.StatsHandler.GetStats))
+
+	router.Get("/audit", jwtMiddleware(c.AuditHandler.GetEntries))
 	return router
 }
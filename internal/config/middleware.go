@@ -47,6 +47,19 @@ func jwtMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		// Сохраняем данные пользователя в контексте запроса
+		next.ServeHTTP(w, r.WithContext(user.NewContext(r.Context(), &claims)))
+	}
+}
+
+// userMiddleware сохраняет в контексте данные пользователя, если в запросе есть валидный токен.
+// В отличие от jwtMiddleware, запросы без токена и от обычных пользователей не отклоняются.
+func userMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := user.ParseToken(r)
+		if err == nil {
+			r = r.WithContext(user.NewContext(r.Context(), claims))
+		}
 		next.ServeHTTP(w, r)
 	}
 }
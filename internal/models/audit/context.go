@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"net"
+	"net/http"
+)
+
+// Request - данные HTTP-запроса, которые сохраняются вместе с изменениями
+type Request struct {
+	ID string `json:"id,omitempty"`
+	IP string `json:"ip,omitempty"`
+}
+
+type requestKey struct{}
+
+// NewContext возвращает копию контекста с данными запроса
+func NewContext(ctx context.Context, request Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, request)
+}
+
+// FromContext достает данные запроса, сохраненные Middleware
+func FromContext(ctx context.Context) (Request, bool) {
+	request, ok := ctx.Value(requestKey{}).(Request)
+	return request, ok
+}
+
+// Middleware сохраняет в контексте идентификатор запроса и IP клиента. Идентификатор
+// выдает middleware.RequestID, а IP с учетом прокси - middleware.RealIP, поэтому
+// они должны стоять в цепочке раньше.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := Request{ID: middleware.GetReqID(r.Context())}
+		// После RealIP адрес может быть указан без порта
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			request.IP = ip.String()
+		}
+		if request.ID != "" {
+			w.Header().Set(middleware.RequestIDHeader, request.ID)
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), request)))
+	})
+}
@@ -0,0 +1,110 @@
+package dbaudit
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/user"
+	"banner-service/pkg/db/postgresql"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+type auditRepository struct {
+	db     postgresql.Client
+	logger *logging.Logger
+}
+
+func NewAuditRepository(db postgresql.Client, logger *logging.Logger) audit.Storage {
+	return &auditRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *auditRepository) GetEntries(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	query := `
+		SELECT id, actor_id, action, entity, entity_id, before, after, COALESCE(request_id, ''), COALESCE(host(ip), ''), created_at
+		FROM audit_log
+		WHERE ($1::int IS NULL OR actor_id = $1)
+		  AND ($2 = '' OR entity = $2)
+		  AND ($3::int IS NULL OR entity_id = $3)
+		  AND ($4::timestamptz IS NULL OR created_at >= $4)
+		  AND ($5::timestamptz IS NULL OR created_at < $5)
+		ORDER BY id DESC
+		LIMIT $6 OFFSET $7`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := r.db.Query(ctx, query, filter.ActorID, filter.Entity, filter.EntityID, filter.From, filter.To, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]audit.Entry, 0)
+	for rows.Next() {
+		var entry audit.Entry
+		err = rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.Entity, &entry.EntityID, &entry.Before, &entry.After,
+			&entry.RequestID, &entry.IP, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Write сохраняет запись журнала в транзакции изменения, чтобы изменение
+// и запись о нем фиксировались или откатывались вместе.
+// Автор, идентификатор запроса и IP берутся из ctx.
+func Write(ctx context.Context, tx pgx.Tx, entry *audit.Entry) error {
+	if claims, ok := user.FromContext(ctx); ok {
+		entry.ActorID = &claims.ID
+	}
+	var requestID, ip *string
+	if request, ok := audit.FromContext(ctx); ok {
+		entry.RequestID, entry.IP = request.ID, request.IP
+		if request.ID != "" {
+			requestID = &request.ID
+		}
+		if request.IP != "" {
+			ip = &request.IP
+		}
+	}
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, entity, entity_id, before, after, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8::inet)
+		RETURNING id, created_at`
+
+	return tx.QueryRow(ctx, query, entry.ActorID, entry.Action, entry.Entity, entry.EntityID, entry.Before, entry.After, requestID, ip).
+		Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// Snapshot возвращает состояние записи в виде JSON. query выбирает одно значение jsonb;
+// если записи нет, возвращается nil.
+func Snapshot(ctx context.Context, tx pgx.Tx, query string, args ...any) (json.RawMessage, error) {
+	var snapshot json.RawMessage
+	err := tx.QueryRow(ctx, query, args...).Scan(&snapshot)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Record записывает изменение сущности: before - ее снимок до изменения,
+// снимок после изменения читается тем же запросом snapshot.
+func Record(ctx context.Context, tx pgx.Tx, action, entity string, id int, before json.RawMessage, snapshot string, args ...any) error {
+	after, err := Snapshot(ctx, tx, snapshot, args...)
+	if err != nil {
+		return err
+	}
+	return Write(ctx, tx, &audit.Entry{Action: action, Entity: entity, EntityID: id, Before: before, After: after})
+}
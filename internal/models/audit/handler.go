@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"banner-service/internal/utils"
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	contextTimeOut = time.Second * 10
+	defaultLimit   = 100
+	maxLimit       = 1000
+)
+
+type Handler struct {
+	logger     *logging.Logger
+	repository Storage
+}
+
+func NewHandler(repository Storage, logger *logging.Logger) *Handler {
+	return &Handler{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+// GetEntries отдает журнал изменений с фильтрами actor_id, entity, entity_id, from и to
+func (h *Handler) GetEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), contextTimeOut)
+	defer cancel()
+
+	entries, err := h.repository.GetEntries(ctx, filter)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			h.logger.Error(err)
+			http.Error(w, "request timeout", http.StatusRequestTimeout)
+			return
+		}
+		h.logger.Error(err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{"items": entries})
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	filter := Filter{Entity: query.Get("entity"), Limit: defaultLimit}
+	var err error
+
+	filter.ActorID, err = utils.ParseOptionalInt(query.Get("actor_id"))
+	if err != nil {
+		return filter, errors.New("invalid actor_id parameter")
+	}
+	filter.EntityID, err = utils.ParseOptionalInt(query.Get("entity_id"))
+	if err != nil {
+		return filter, errors.New("invalid entity_id parameter")
+	}
+	filter.From, err = utils.ParseOptionalTime(query.Get("from"))
+	if err != nil {
+		return filter, errors.New("invalid from parameter")
+	}
+	filter.To, err = utils.ParseOptionalTime(query.Get("to"))
+	if err != nil {
+		return filter, errors.New("invalid to parameter")
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return filter, errors.New("from must be before to")
+	}
+
+	if value := query.Get("limit"); value != "" {
+		filter.Limit, err = strconv.Atoi(value)
+		if err != nil || filter.Limit <= 0 {
+			return filter, errors.New("invalid limit parameter")
+		}
+		filter.Limit = min(filter.Limit, maxLimit)
+	}
+	if value := query.Get("offset"); value != "" {
+		filter.Offset, err = strconv.Atoi(value)
+		if err != nil || filter.Offset < 0 {
+			return filter, errors.New("invalid offset parameter")
+		}
+	}
+
+	return filter, nil
+}
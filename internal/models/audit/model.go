@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия, которые записываются в журнал
+const (
+	ActionCreate          = "create"
+	ActionUpdate          = "update"
+	ActionDelete          = "delete"
+	ActionRestore         = "restore"
+	ActionPurge           = "purge"
+	ActionRestoreRevision = "restore_revision"
+	ActionChangeStatus    = "change_status"
+)
+
+// Сущности, изменения которых записываются в журнал
+const (
+	EntityBanner      = "banner"
+	EntityVariant     = "banner_variant"
+	EntityTranslation = "banner_translation"
+	EntityTag         = "tag"
+	EntityFeature     = "feature"
+	EntityTemplate    = "template"
+	EntityUser        = "user"
+)
+
+// Entry - запись журнала изменений. Before и After - состояние сущности до и после
+// изменения: у созданной сущности нет Before, у удаленной окончательно - After.
+// ActorID пустой у изменений, сделанных без пользователя (например, очисткой корзины).
+type Entry struct {
+	ID        int64           `json:"id"`
+	ActorID   *int            `json:"actor_id"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entity_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	RequestID string          `json:"request_id,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Filter задает выборку журнала; пустые поля не ограничивают ее
+type Filter struct {
+	ActorID  *int
+	Entity   string
+	EntityID *int
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
@@ -0,0 +1,8 @@
+package audit
+
+import "context"
+
+type Storage interface {
+	// GetEntries возвращает записи журнала от новых к старым
+	GetEntries(ctx context.Context, filter Filter) ([]Entry, error)
+}
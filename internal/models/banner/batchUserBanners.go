@@ -0,0 +1,99 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const maxPlacements = 50
+
+// UserBannerResult - результат подбора баннера для одной пары (фича, тег) в ответе /user_banners
+type UserBannerResult struct {
+	Status int     `json:"status"`
+	Banner *Banner `json:"banner,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// GetUserBanners подбирает баннеры сразу для нескольких пар (фича, тег). Пары из кэша
+// отдаются сразу, кандидаты для остальных загружаются из базы данных одним запросом.
+func (h *Handler) GetUserBanners(w http.ResponseWriter, r *http.Request, adminChecker AdminChecker) {
+	isAdmin, err := adminChecker.CheckIfAdmin(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var placements []Placement
+	err = json.NewDecoder(r.Body).Decode(&placements)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(placements) == 0 || len(placements) > maxPlacements {
+		http.Error(w, fmt.Sprintf("request must contain from 1 to %d feature and tag pairs", maxPlacements), http.StatusBadRequest)
+		return
+	}
+	useLastRevision, _ := strconv.ParseBool(r.URL.Query().Get("use_last_revision"))
+	useLastRevision = useLastRevision && isAdmin
+	locale := NegotiateLocale(r, h.Locales)
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	candidates := make(map[Placement][]*Banner, len(placements))
+	var misses []Placement
+	seen := make(map[Placement]bool, len(placements))
+	for _, placement := range placements {
+		if seen[placement] {
+			continue
+		}
+		seen[placement] = true
+		if !useLastRevision {
+			if cached, ok := h.Cache.GetBanners(ctx, userBannerCacheKey(placement, locale)); ok {
+				candidates[placement] = cached
+				continue
+			}
+		}
+		misses = append(misses, placement)
+	}
+
+	if len(misses) > 0 {
+		loaded, err := h.repository.GetBannerCandidatesBatch(ctx, misses, useLastRevision)
+		if err != nil {
+			handleErrors(err, h.logger, w)
+			return
+		}
+		chain := localeChain(locale, h.Locales)
+		for _, placement := range misses {
+			found := localizeBanners(loaded[placement], chain)
+			candidates[placement] = found
+			if !useLastRevision && len(found) > 0 {
+				h.Cache.SetBanners(ctx, userBannerCacheKey(placement, locale), found)
+			}
+		}
+	}
+
+	results := make(map[string]UserBannerResult, len(candidates))
+	for placement, list := range candidates {
+		key := fmt.Sprintf("%d:%d", placement.FeatureID, placement.TagID)
+		banner, err := h.showBanner(ctx, r, list, isAdmin, locale)
+		if err != nil {
+			h.logger.Error(err)
+			results[key] = UserBannerResult{Status: http.StatusInternalServerError, Error: "internal server error"}
+			continue
+		}
+		if banner == nil {
+			results[key] = UserBannerResult{Status: http.StatusNotFound, Error: "Banner not available"}
+			continue
+		}
+		h.trackImpression(r, banner, placement.FeatureID, placement.TagID)
+		results[key] = UserBannerResult{Status: http.StatusOK, Banner: h.withClickURL(r, banner, placement.FeatureID, placement.TagID)}
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+	utils.RespondJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,113 @@
+package banner
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/job"
+	"banner-service/internal/models/user"
+	"banner-service/internal/utils"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	JobTypeDeleteBanners = "delete_banners"
+	bulkDeleteBatchSize  = 100
+)
+
+// BulkDeleteParams - параметры задачи массового удаления баннеров. Автор и запрос
+// сохраняются, чтобы удаления, выполненные задачей в фоне, были записаны в журнал от их имени.
+type BulkDeleteParams struct {
+	FeatureID *int           `json:"feature_id,omitempty"`
+	TagID     *int           `json:"tag_id,omitempty"`
+	ActorID   *int           `json:"actor_id,omitempty"`
+	Request   *audit.Request `json:"request,omitempty"`
+}
+
+// BulkDeleteBanners ставит в очередь задачу удаления всех баннеров с заданной фичей и/или тегом
+func (h *Handler) BulkDeleteBanners(w http.ResponseWriter, r *http.Request) {
+	var params BulkDeleteParams
+	var err error
+
+	params.FeatureID, err = utils.ParseOptionalInt(r.URL.Query().Get("feature_id"))
+	if err != nil {
+		http.Error(w, "Invalid feature_id parameter", http.StatusBadRequest)
+		return
+	}
+	params.TagID, err = utils.ParseOptionalInt(r.URL.Query().Get("tag_id"))
+	if err != nil {
+		http.Error(w, "Invalid tag_id parameter", http.StatusBadRequest)
+		return
+	}
+	if params.FeatureID == nil && params.TagID == nil {
+		http.Error(w, "feature_id or tag_id parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	if claims, ok := user.FromContext(r.Context()); ok {
+		params.ActorID = &claims.ID
+	}
+	if request, ok := audit.FromContext(r.Context()); ok {
+		params.Request = &request
+	}
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	bulkJob := &job.Job{Type: JobTypeDeleteBanners, Params: rawParams}
+	err = h.Jobs.Create(ctx, bulkJob)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/jobs/%d", bulkJob.ID))
+	response := map[string]interface{}{"job_id": bulkJob.ID, "status": bulkJob.Status}
+	utils.RespondJSON(w, http.StatusAccepted, response)
+}
+
+// NewBulkDeleteTask возвращает задачу, удаляющую баннеры пачками и очищающую их из кэша
+func NewBulkDeleteTask(repository Storage, cache *CacheBanner, logger *logging.Logger) job.Task {
+	return func(ctx context.Context, bulkJob *job.Job, progress func(processed int) error) error {
+		var params BulkDeleteParams
+		err := json.Unmarshal(bulkJob.Params, &params)
+		if err != nil {
+			return err
+		}
+		if params.ActorID != nil {
+			ctx = user.NewContext(ctx, &user.CustomClaims{ID: *params.ActorID, IsAdmin: true})
+		}
+		if params.Request != nil {
+			ctx = audit.NewContext(ctx, *params.Request)
+		}
+
+		processed := bulkJob.Processed
+		for {
+			ids, err := repository.DeleteBannersBatch(ctx, params.FeatureID, params.TagID, bulkDeleteBatchSize)
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+
+			cache.DeleteBannersByID(ctx, ids)
+			processed += len(ids)
+			logger.Tracef("bulk delete: %d banners deleted", processed)
+
+			err = progress(processed)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package banner
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,8 +14,9 @@ type CacheBanner struct {
 	quit  chan struct{}
 }
 
+// cacheItem хранит всех кандидатов для пары (фича, тег), выбор по таргетингу делается на каждый запрос
 type cacheItem struct {
-	banner     *Banner
+	banners    []*Banner
 	expiration time.Time
 }
 
@@ -28,17 +30,24 @@ func NewBannerCache(ttl time.Duration) *CacheBanner {
 	return cache
 }
 
-func (bc *CacheBanner) SetBanner(ctx context.Context, key string, banner *Banner) {
-	expiration := time.Now().Add(bc.ttl)
+func (bc *CacheBanner) SetBanners(ctx context.Context, key string, banners []*Banner) {
+	now := time.Now()
+	expiration := now.Add(bc.ttl)
+	// Запись не должна пережить границу окна показа ни одного из баннеров
+	for _, banner := range banners {
+		if boundary, ok := banner.nextScheduleChange(now); ok && boundary.Before(expiration) {
+			expiration = boundary
+		}
+	}
 	bc.mutex.Lock()
 	bc.cache[key] = &cacheItem{
-		banner:     banner,
+		banners:    banners,
 		expiration: expiration,
 	}
 	bc.mutex.Unlock()
 }
 
-func (bc *CacheBanner) GetBanner(ctx context.Context, key string) (*Banner, bool) {
+func (bc *CacheBanner) GetBanners(ctx context.Context, key string) ([]*Banner, bool) {
 	bc.mutex.RLock()
 	item, ok := bc.cache[key]
 	bc.mutex.RUnlock()
@@ -49,7 +58,7 @@ func (bc *CacheBanner) GetBanner(ctx context.Context, key string) (*Banner, bool
 		bc.DeleteBanner(ctx, key)
 		return nil, false
 	}
-	return item.banner, true
+	return item.banners, true
 }
 
 func (bc *CacheBanner) DeleteBanner(ctx context.Context, key string) {
@@ -58,6 +67,44 @@ func (bc *CacheBanner) DeleteBanner(ctx context.Context, key string) {
 	bc.mutex.Unlock()
 }
 
+// DeleteBannersByID удаляет из кэша все записи с указанными баннерами
+func (bc *CacheBanner) DeleteBannersByID(ctx context.Context, ids []int) {
+	deleted := make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		deleted[id] = struct{}{}
+	}
+
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	for key, item := range bc.cache {
+		for _, banner := range item.banners {
+			if _, ok := deleted[banner.ID]; ok {
+				delete(bc.cache, key)
+				break
+			}
+		}
+	}
+}
+
+// DeletePlacements удаляет из кэша кандидатов пар placements на всех языках
+func (bc *CacheBanner) DeletePlacements(ctx context.Context, placements []Placement) {
+	prefixes := make([]string, 0, len(placements))
+	for _, placement := range placements {
+		prefixes = append(prefixes, userBannerCacheKey(placement, ""))
+	}
+
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	for key := range bc.cache {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				delete(bc.cache, key)
+				break
+			}
+		}
+	}
+}
+
 func (bc *CacheBanner) runExpirationLoop() {
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
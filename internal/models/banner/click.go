@@ -0,0 +1,192 @@
+package banner
+
+import (
+	"banner-service/internal/models/impression"
+	"banner-service/internal/models/user"
+	"banner-service/internal/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidSignature = errors.New("invalid signature")
+	errLinkExpired      = errors.New("link has expired")
+	errForeignLink      = errors.New("link was issued to another user")
+)
+
+// LinkSigner подписывает ссылки перехода по баннеру, чтобы их нельзя было подделать.
+// Подпись покрывает срок действия ссылки и пользователя, которому она выдана.
+type LinkSigner struct {
+	secret  []byte
+	baseURL string
+	ttl     time.Duration
+}
+
+// NewLinkSigner создает подписчика ссылок. baseURL - внешний адрес сервиса,
+// без него ссылки относительные; ttl - время жизни ссылки.
+func NewLinkSigner(secret, baseURL string, ttl time.Duration) *LinkSigner {
+	return &LinkSigner{secret: []byte(secret), baseURL: strings.TrimSuffix(baseURL, "/"), ttl: ttl}
+}
+
+// clickLink - параметры ссылки перехода, защищенные подписью
+type clickLink struct {
+	BannerID  int
+	FeatureID int
+	TagID     int
+	VariantID *int
+	UserID    *int
+	Expires   int64
+}
+
+func (s *LinkSigner) sign(link clickLink) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%d:%d:%d:%d", link.BannerID, link.FeatureID, link.TagID, link.Expires)
+	if link.VariantID != nil {
+		fmt.Fprintf(mac, ":v%d", *link.VariantID)
+	}
+	if link.UserID != nil {
+		fmt.Fprintf(mac, ":u%d", *link.UserID)
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify проверяет подпись ссылки и то, что срок ее действия не истек
+func (s *LinkSigner) verify(link clickLink, signature string, now time.Time) error {
+	expected := s.sign(link)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errInvalidSignature
+	}
+	if now.Unix() > link.Expires {
+		return errLinkExpired
+	}
+	return nil
+}
+
+// period возвращает начало периода выдачи ссылок, в который попадает now. Ссылки, выданные
+// в одном периоде, совпадают, поэтому клиент может повторно использовать ответ с ними;
+// срок действия отсчитывается от начала периода и не меньше половины ttl.
+func (s *LinkSigner) period(now time.Time) time.Time {
+	step := s.ttl / 2
+	if step < time.Second {
+		step = time.Second
+	}
+	return now.Truncate(step)
+}
+
+// ClickURL возвращает ссылку перехода по баннеру через /banner/{id}/click для пользователя userID
+func (s *LinkSigner) ClickURL(bannerID, featureID, tagID int, variantID, userID *int) string {
+	link := clickLink{
+		BannerID:  bannerID,
+		FeatureID: featureID,
+		TagID:     tagID,
+		VariantID: variantID,
+		UserID:    userID,
+		Expires:   s.period(time.Now()).Add(s.ttl).Unix(),
+	}
+	query := url.Values{}
+	query.Set("feature_id", strconv.Itoa(featureID))
+	query.Set("tag_id", strconv.Itoa(tagID))
+	if variantID != nil {
+		query.Set("variant_id", strconv.Itoa(*variantID))
+	}
+	if userID != nil {
+		query.Set("uid", strconv.Itoa(*userID))
+	}
+	query.Set("exp", strconv.FormatInt(link.Expires, 10))
+	query.Set("sig", s.sign(link))
+	return fmt.Sprintf("%s/banner/%d/click?%s", s.baseURL, bannerID, query.Encode())
+}
+
+// ClickBanner записывает переход по баннеру и перенаправляет пользователя на URL баннера.
+// Адрес перенаправления берется из базы данных, а не из запроса, поэтому ссылка
+// не может быть использована как открытый редирект.
+func (h *Handler) ClickBanner(w http.ResponseWriter, r *http.Request) {
+	if h.Links == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	link := clickLink{BannerID: id}
+	link.FeatureID, err = strconv.Atoi(query.Get("feature_id"))
+	if err != nil {
+		http.Error(w, "Invalid feature_id parameter", http.StatusBadRequest)
+		return
+	}
+	link.TagID, err = strconv.Atoi(query.Get("tag_id"))
+	if err != nil {
+		http.Error(w, "Invalid tag_id parameter", http.StatusBadRequest)
+		return
+	}
+	link.VariantID, err = utils.ParseOptionalInt(query.Get("variant_id"))
+	if err != nil {
+		http.Error(w, "Invalid variant_id parameter", http.StatusBadRequest)
+		return
+	}
+	link.UserID, err = utils.ParseOptionalInt(query.Get("uid"))
+	if err != nil {
+		http.Error(w, "Invalid uid parameter", http.StatusBadRequest)
+		return
+	}
+	link.Expires, err = strconv.ParseInt(query.Get("exp"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid exp parameter", http.StatusBadRequest)
+		return
+	}
+	err = h.Links.verify(link, query.Get("sig"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	// Ссылку, выданную пользователю, может использовать только он сам
+	claims, hasUser := user.FromContext(r.Context())
+	if link.UserID != nil && (!hasUser || claims.ID != *link.UserID) {
+		http.Error(w, errForeignLink.Error(), http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	target, err := h.repository.GetBannerURL(ctx, id, link.VariantID)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		http.Error(w, "Banner has no URL", http.StatusNotFound)
+		return
+	}
+
+	if h.Clicks != nil {
+		click := impression.Click{
+			BannerID:  id,
+			FeatureID: link.FeatureID,
+			TagID:     link.TagID,
+			VariantID: link.VariantID,
+			UserID:    link.UserID,
+			ClickedAt: time.Now(),
+		}
+		// Ошибка записи не должна мешать переходу пользователя
+		if err = h.Clicks.SaveClick(ctx, click); err != nil {
+			h.logger.Error(err)
+		}
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
@@ -0,0 +1,112 @@
+package banner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Поля, по которым разрешена сортировка списка баннеров
+var sortFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"title":      true,
+}
+
+// Cursor - позиция последнего баннера страницы для keyset-пагинации
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    int    `json:"id"`
+}
+
+// ParseSort разбирает параметр sort вида "created_at" или "-created_at".
+// Сортировка по релевантности поиска всегда идет по убыванию.
+func ParseSort(value string) (field string, desc bool, err error) {
+	if value == "" {
+		return "id", false, nil
+	}
+	if strings.TrimPrefix(value, "-") == "relevance" {
+		return "relevance", true, nil
+	}
+	field = strings.TrimPrefix(value, "-")
+	if !sortFields[field] {
+		return "", false, errors.New("invalid sort parameter")
+	}
+	return field, strings.HasPrefix(value, "-"), nil
+}
+
+func sortKey(field string, desc bool) string {
+	if desc {
+		return "-" + field
+	}
+	return field
+}
+
+// NewCursor строит курсор, указывающий на баннер b при сортировке из filter
+func NewCursor(b *Banner, filter Filter) *Cursor {
+	cursor := &Cursor{Sort: sortKey(filter.Sort, filter.Desc), ID: b.ID}
+	switch filter.Sort {
+	case "created_at":
+		cursor.Value = b.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = b.UpdatedAt.Format(time.RFC3339Nano)
+	case "title":
+		cursor.Value = b.Title
+	case "relevance":
+		if b.Rank != nil {
+			cursor.Value = strconv.FormatFloat(*b.Rank, 'g', -1, 64)
+		}
+	}
+	return cursor
+}
+
+// SortValue возвращает значение поля сортировки в виде, пригодном для параметра запроса
+func (c *Cursor) SortValue() (interface{}, error) {
+	switch strings.TrimPrefix(c.Sort, "-") {
+	case "created_at", "updated_at":
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	case "title":
+		return c.Value, nil
+	case "relevance":
+		rank, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return rank, nil
+	default:
+		return c.ID, nil
+	}
+}
+
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает курсор и проверяет, что он выдан для той же сортировки
+func DecodeCursor(value, field string, desc bool) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || cursor.Sort != sortKey(field, desc) {
+		return nil, ErrInvalidCursor
+	}
+	if _, err = cursor.SortValue(); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
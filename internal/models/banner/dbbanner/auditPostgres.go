@@ -0,0 +1,49 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
+	"context"
+	"encoding/json"
+	"github.com/jackc/pgx/v5"
+)
+
+// Запросы снимков для журнала изменений; у баннера в снимок добавляются его теги.
+// Снимок блокирует строку, чтобы до изменения ее не поменяла другая транзакция.
+const (
+	bannerSnapshot = `
+		SELECT (to_jsonb(b) - 'search_vector') || jsonb_build_object('tag_ids',
+		       COALESCE((SELECT array_agg(bt.tag_id ORDER BY bt.tag_id) FROM banner_tags bt WHERE bt.banner_id = b.id), '{}'))
+		FROM banners b
+		WHERE b.id = $1
+		FOR UPDATE OF b`
+	variantSnapshot     = `SELECT to_jsonb(v) FROM banner_variants v WHERE v.id = $1 FOR UPDATE`
+	translationSnapshot = `SELECT to_jsonb(t) FROM banner_translations t WHERE t.banner_id = $1 AND t.locale = $2 FOR UPDATE`
+	tagSnapshot         = `SELECT to_jsonb(t) FROM tags t WHERE t.id = $1 FOR UPDATE`
+	featureSnapshot     = `SELECT to_jsonb(f) FROM features f WHERE f.id = $1 FOR UPDATE`
+	templateSnapshot    = `SELECT to_jsonb(t) FROM templates t WHERE t.id = $1 FOR UPDATE`
+)
+
+// bannerSnapshots возвращает снимки баннеров ids в том же порядке
+func bannerSnapshots(ctx context.Context, tx pgx.Tx, ids []int) ([]json.RawMessage, error) {
+	snapshots := make([]json.RawMessage, 0, len(ids))
+	for _, id := range ids {
+		snapshot, err := dbaudit.Snapshot(ctx, tx, bannerSnapshot, id)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// recordBanners записывает одно и то же действие над несколькими баннерами
+func recordBanners(ctx context.Context, tx pgx.Tx, action string, ids []int, before []json.RawMessage) error {
+	for i, id := range ids {
+		err := dbaudit.Record(ctx, tx, action, audit.EntityBanner, id, before[i], bannerSnapshot, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/db/postgresql"
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+type featureRepository struct {
+	db     postgresql.Client
+	logger *logging.Logger
+}
+
+func NewFeatureRepository(db postgresql.Client, logger *logging.Logger) banner.FeatureStorage {
+	return &featureRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *featureRepository) GetFeatures(ctx context.Context) ([]banner.Feature, error) {
+	query := `SELECT id, name, schema FROM features ORDER BY id`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	features := make([]banner.Feature, 0)
+	for rows.Next() {
+		var feature banner.Feature
+		err = rows.Scan(&feature.ID, &feature.Name, &feature.Schema)
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, feature)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return features, nil
+}
+
+func (r *featureRepository) GetFeature(ctx context.Context, id int) (*banner.Feature, error) {
+	query := `SELECT id, name, schema FROM features WHERE id = $1`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var feature banner.Feature
+	err := r.db.QueryRow(ctx, query, id).Scan(&feature.ID, &feature.Name, &feature.Schema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, banner.ErrFeatureNotFound
+		}
+		return nil, err
+	}
+
+	return &feature, nil
+}
+
+func (r *featureRepository) CreateFeature(ctx context.Context, feature *banner.Feature) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO features (name, schema) VALUES ($1, $2) RETURNING id`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, feature.Name, feature.Schema).Scan(&feature.ID)
+	if err != nil {
+		return uniqueError(err)
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionCreate, audit.EntityFeature, feature.ID, nil, featureSnapshot, feature.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *featureRepository) UpdateFeature(ctx context.Context, feature *banner.Feature) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, featureSnapshot, feature.ID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return banner.ErrFeatureNotFound
+	}
+
+	query := `UPDATE features SET name = $1, schema = $2 WHERE id = $3`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err = tx.Exec(ctx, query, feature.Name, feature.Schema, feature.ID)
+	if err != nil {
+		return uniqueError(err)
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionUpdate, audit.EntityFeature, feature.ID, before, featureSnapshot, feature.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *featureRepository) DeleteFeature(ctx context.Context, id int, cascade bool) ([]int, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Фичу используют и баннеры, опубликованная ревизия которых еще показывает ее пользователям
+	query := `
+		SELECT b.id FROM banners b
+		WHERE b.feature_id = $1
+		   OR EXISTS (SELECT 1 FROM banner_revisions r WHERE r.banner_id = b.id AND r.version = b.published_version AND r.feature_id = $1)
+		ORDER BY b.id
+		FOR UPDATE`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	bannerIDs, err := collectIDs(ctx, tx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bannerIDs) > 0 {
+		if !cascade {
+			return nil, &banner.InUseError{BannerIDs: bannerIDs}
+		}
+		err = purgeCascade(ctx, tx, bannerIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before, err := dbaudit.Snapshot(ctx, tx, featureSnapshot, id)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, banner.ErrFeatureNotFound
+	}
+
+	query = `DELETE FROM features WHERE id = $1`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err = tx.Exec(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbaudit.Write(ctx, tx, &audit.Entry{Action: audit.ActionDelete, Entity: audit.EntityFeature, EntityID: id, Before: before})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return bannerIDs, nil
+}
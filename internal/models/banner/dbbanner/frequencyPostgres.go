@@ -0,0 +1,64 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/db/postgresql"
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// frequencyRepository хранит счетчики лимита показов в PostgreSQL,
+// чтобы несколько экземпляров сервиса видели одни и те же значения
+type frequencyRepository struct {
+	db     postgresql.Client
+	logger *logging.Logger
+}
+
+func NewFrequencyRepository(db postgresql.Client, logger *logging.Logger) banner.FrequencyCounterStorage {
+	return &frequencyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (f *frequencyRepository) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	// Увеличение и проверка счетчика выполняются одним запросом; если лимит исчерпан,
+	// строка не обновляется и запрос ничего не возвращает
+	query := `
+		INSERT INTO frequency_counters (key, count, reset_at)
+		VALUES ($1, 1, NOW() + make_interval(secs => $3))
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN frequency_counters.reset_at <= NOW() THEN 1 ELSE frequency_counters.count + 1 END,
+			reset_at = CASE WHEN frequency_counters.reset_at <= NOW() THEN EXCLUDED.reset_at ELSE frequency_counters.reset_at END
+		WHERE frequency_counters.reset_at <= NOW() OR frequency_counters.count < $2
+		RETURNING count`
+
+	f.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var count int
+	err := f.db.QueryRow(ctx, query, key, limit, window.Seconds()).Scan(&count)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (f *frequencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM frequency_counters WHERE reset_at < NOW()`
+	f.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	tag, err := f.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -1,12 +1,18 @@
 package dbbanner
 
 import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
 	"banner-service/internal/models/banner"
+	"banner-service/internal/models/user"
 	"banner-service/pkg/db/postgresql"
 	"banner-service/pkg/logging"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
+	"time"
 )
 
 type bannerRepository struct {
@@ -21,56 +27,293 @@ func NewBannerRepository(db postgresql.Client, logger *logging.Logger) banner.St
 	}
 }
 
-func (b *bannerRepository) GetBannerFromDB(ctx context.Context, tagID, featureID int, useLastRevision bool) (*banner.Banner, error) {
-	query := `
-    SELECT b.id, b.title, b.text, b.url, b.is_active, b.created_at, b.updated_at, array_agg(t.id) as tag_ids, f.id as feature_id, f.name as feature_name
+// publishedBannerQuery выбирает опубликованные ревизии баннеров. Все, что видит пользователь,
+// включая расписание, таргетинг, лимит показов, варианты и переводы, берется из ревизии.
+// Ревизии ссылаются на фичу и теги без внешних ключей, поэтому фича присоединяется через LEFT JOIN:
+// баннер не должен пропадать из выдачи молча, даже если его фичи уже нет.
+const publishedBannerQuery = `
+    SELECT b.id, r.title, r.text, r.url, r.content, r.is_active, r.starts_at, r.ends_at, r.timezone, r.targeting, r.frequency_cap, r.version, b.created_at, r.created_at, r.tag_ids, r.feature_id, COALESCE(f.name, '') as feature_name, r.variants, r.translations
+    FROM banners b
+    JOIN banner_revisions r ON r.banner_id = b.id AND r.version = b.published_version
+    LEFT JOIN features f ON r.feature_id = f.id
+`
+
+// lastRevisionBannerQuery выбирает последние ревизии баннеров, которые хранятся в самой таблице banners
+const lastRevisionBannerQuery = `
+    SELECT b.id, b.title, b.text, b.url, b.content, b.is_active, b.starts_at, b.ends_at, b.timezone, b.targeting, b.frequency_cap, b.version, b.created_at, b.updated_at, array_agg(t.id) as tag_ids, f.id as feature_id, f.name as feature_name,
+           ` + variantsJSON + ` as variants, ` + translationsJSON + ` as translations
     FROM banners b
     JOIN features f ON b.feature_id = f.id
     JOIN banner_tags bt ON b.id = bt.banner_id
     JOIN tags t ON bt.tag_id = t.id
-    WHERE f.id = $1 AND t.id = $2
+`
+
+func (b *bannerRepository) GetBannerCandidates(ctx context.Context, tagID, featureID int, useLastRevision bool) ([]*banner.Banner, error) {
+	var query string
+	if useLastRevision {
+		query = lastRevisionBannerQuery + `
+    WHERE f.id = $1 AND t.id = $2 AND b.deleted_at IS NULL AND b.status <> 'archived'
     GROUP BY b.id, f.id
-    ORDER BY
+    ORDER BY b.updated_at DESC
+`
+	} else {
+		// Иначе отдаем последнюю опубликованную ревизию
+		query = publishedBannerQuery + `
+    WHERE r.feature_id = $1 AND $2 = ANY(r.tag_ids) AND b.deleted_at IS NULL
+    ORDER BY b.created_at DESC
 `
+	}
+
+	candidates, err := b.queryUserBanners(ctx, query, featureID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, banner.ErrBannerNotFound
+	}
+
+	return candidates, nil
+}
+
+// GetBannerCandidatesBatch выбирает кандидатов сразу для всех пар одним запросом и раскладывает их по парам
+func (b *bannerRepository) GetBannerCandidatesBatch(ctx context.Context, placements []banner.Placement, useLastRevision bool) (map[banner.Placement][]*banner.Banner, error) {
+	featureIDs := make([]int, 0, len(placements))
+	tagIDs := make([]int, 0, len(placements))
+	for _, p := range placements {
+		featureIDs = append(featureIDs, p.FeatureID)
+		tagIDs = append(tagIDs, p.TagID)
+	}
 
-	var orderBy string
+	var query string
 	if useLastRevision {
-		orderBy = "b.updated_at DESC"
+		query = lastRevisionBannerQuery + `
+    WHERE b.deleted_at IS NULL AND b.status <> 'archived' AND EXISTS (
+        SELECT 1 FROM unnest($1::int[], $2::int[]) AS p(feature_id, tag_id)
+        JOIN banner_tags pt ON pt.tag_id = p.tag_id
+        WHERE pt.banner_id = b.id AND p.feature_id = b.feature_id
+    )
+    GROUP BY b.id, f.id
+    ORDER BY b.updated_at DESC
+`
 	} else {
-		orderBy = "b.created_at DESC"
+		query = publishedBannerQuery + `
+    WHERE b.deleted_at IS NULL AND EXISTS (
+        SELECT 1 FROM unnest($1::int[], $2::int[]) AS p(feature_id, tag_id)
+        WHERE p.feature_id = r.feature_id AND p.tag_id = ANY(r.tag_ids)
+    )
+    ORDER BY b.created_at DESC
+`
+	}
+
+	banners, err := b.queryUserBanners(ctx, query, featureIDs, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[banner.Placement][]*banner.Banner, len(placements))
+	for _, p := range placements {
+		if _, ok := candidates[p]; ok {
+			continue
+		}
+		for _, bn := range banners {
+			if bn.FeatureID.ID == p.FeatureID && hasTag(bn.Tags, p.TagID) {
+				candidates[p] = append(candidates[p], bn)
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+func hasTag(tags []banner.Tag, id int) bool {
+	for _, tag := range tags {
+		if tag.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *bannerRepository) GetPublishedBanner(ctx context.Context, id int) (*banner.Banner, error) {
+	banners, err := b.queryUserBanners(ctx, publishedBannerQuery+`    WHERE b.id = $1 AND b.deleted_at IS NULL`, id)
+	if err != nil {
+		return nil, err
 	}
+	if len(banners) == 0 {
+		return nil, banner.ErrBannerNotFound
+	}
+
+	return banners[0], nil
+}
 
-	query += orderBy + " LIMIT 1"
+// variantsJSON и translationsJSON собирают текущие варианты и переводы баннера b
+// в JSON-массивы; в таком виде они хранятся в ревизиях
+const (
+	variantsJSON     = `COALESCE((SELECT jsonb_agg(jsonb_strip_nulls(to_jsonb(v)) ORDER BY v.id) FROM banner_variants v WHERE v.banner_id = b.id), '[]')`
+	translationsJSON = `COALESCE((SELECT jsonb_agg(to_jsonb(tr) ORDER BY tr.locale) FROM banner_translations tr WHERE tr.banner_id = b.id), '[]')`
+)
 
+// queryUserBanners загружает баннеры для показа пользователям. Варианты и переводы приходят
+// вместе с баннером, теги загружаются отдельным запросом сразу для всех баннеров.
+func (b *bannerRepository) queryUserBanners(ctx context.Context, query string, args ...interface{}) ([]*banner.Banner, error) {
 	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
 
-	row := b.db.QueryRow(ctx, query, featureID, tagID)
+	rows, err := b.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var banners []*banner.Banner
+	var bannerTags [][]int
+	var allTagIDs []int
+	for rows.Next() {
+		var bn banner.Banner
+		var tagIDs []int
+		err = rows.Scan(&bn.ID, &bn.Title, &bn.Text, &bn.URL, &bn.Content, &bn.IsActive, &bn.StartsAt, &bn.EndsAt, &bn.Timezone, &bn.Targeting, &bn.FrequencyCap,
+			&bn.Version, &bn.CreatedAt, &bn.UpdatedAt, &tagIDs, &bn.FeatureID.ID, &bn.FeatureID.Name, &bn.Variants, &bn.Translations)
+		if err != nil {
+			return nil, err
+		}
 
-	var bn banner.Banner
-	var tagIDs []int
-	var featureName string
-	err := row.Scan(&bn.ID, &bn.Title, &bn.Text, &bn.URL, &bn.IsActive, &bn.CreatedAt, &bn.UpdatedAt, &tagIDs, &bn.FeatureID.ID, &featureName)
+		err = bn.NormalizeSchedule()
+		if err != nil {
+			return nil, err
+		}
+		banners = append(banners, &bn)
+		bannerTags = append(bannerTags, tagIDs)
+		allTagIDs = append(allTagIDs, tagIDs...)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(banners) == 0 {
+		return banners, nil
+	}
+
+	tags, err := b.getTagsByIDs(ctx, allTagIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, tagID := range tagIDs {
+	// Тег из ревизии мог быть удален, такой тег просто не показывается в баннере
+	for i, bn := range banners {
+		for _, tagID := range bannerTags[i] {
+			if tag, ok := tags[tagID]; ok {
+				bn.Tags = append(bn.Tags, tag)
+			}
+		}
+	}
+
+	return banners, nil
+}
+
+func (b *bannerRepository) getTagsByIDs(ctx context.Context, ids []int) (map[int]banner.Tag, error) {
+	query := "SELECT id, name FROM tags WHERE id = ANY($1)"
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := b.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int]banner.Tag)
+	for rows.Next() {
 		var tag banner.Tag
-		err := b.db.QueryRow(ctx, "SELECT id, name FROM tags WHERE id = $1", tagID).Scan(&tag.ID, &tag.Name)
-		if err != nil {
+		if err = rows.Scan(&tag.ID, &tag.Name); err != nil {
 			return nil, err
 		}
-		bn.Tags = append(bn.Tags, tag)
+		tags[tag.ID] = tag
 	}
 
-	bn.FeatureID.Name = featureName
+	return tags, rows.Err()
+}
 
-	return &bn, nil
+// sortColumns сопоставляет поля сортировки с колонками таблицы banners
+var sortColumns = map[string]string{
+	"id":         "banners.id",
+	"created_at": "banners.created_at",
+	"updated_at": "banners.updated_at",
+	"title":      "banners.title",
 }
 
-func (b *bannerRepository) GetBannersByFiltering(ctx context.Context, featureID, tagID, limit, offset int) ([]*banner.Banner, error) {
-	var banners []*banner.Banner
+func (b *bannerRepository) GetBannersByFiltering(ctx context.Context, filter banner.Filter) (*banner.Page, error) {
+	page := &banner.Page{Banners: make([]*banner.Banner, 0)}
+
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// Фильтр по тегу проверяется через EXISTS, чтобы в ответ попадали все теги баннера
+	featureID, tagID := arg(filter.FeatureID), arg(filter.TagID)
+	deleted := "banners.deleted_at IS NULL"
+	if filter.Deleted {
+		deleted = "banners.deleted_at IS NOT NULL"
+	}
+	where := fmt.Sprintf(`
+       WHERE
+           %[3]s
+           AND (%[1]s::int IS NULL OR banners.feature_id = %[1]s)
+           AND (%[2]s::int IS NULL OR EXISTS (SELECT 1 FROM banner_tags bt WHERE bt.banner_id = banners.id AND bt.tag_id = %[2]s))`,
+		featureID, tagID, deleted)
+	if filter.Status != nil {
+		where += " AND banners.status = " + arg(*filter.Status)
+	}
+
+	// Полнотекстовый поиск дополняется триграммным, чтобы находить слова с опечатками
+	rank, highlight := "NULL::float8", "NULL::text, NULL::text"
+	if filter.Query != "" {
+		q := arg(filter.Query)
+		where += fmt.Sprintf(`
+           AND (banners.search_vector @@ plainto_tsquery('simple', %[1]s)
+                OR %[1]s <%% banners.title OR %[1]s <%% banners.text)`, q)
+		rank = fmt.Sprintf(`(ts_rank(banners.search_vector, plainto_tsquery('simple', %[1]s))
+                   + GREATEST(word_similarity(%[1]s, banners.title), word_similarity(%[1]s, banners.text)))::float8`, q)
+		if filter.Highlight {
+			highlight = fmt.Sprintf(`ts_headline('simple', banners.title, plainto_tsquery('simple', %[1]s)),
+              ts_headline('simple', banners.text, plainto_tsquery('simple', %[1]s))`, q)
+		}
+	}
+
+	countQuery := `SELECT COUNT(*) FROM banners` + where
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", countQuery))
 
+	err := b.db.QueryRow(ctx, countQuery, args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	column, ok := sortColumns[filter.Sort]
+	if filter.Sort == "relevance" {
+		column = rank
+	} else if !ok {
+		column = sortColumns["id"]
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	offset := filter.Offset
+	if filter.After != nil {
+		value, err := filter.After.SortValue()
+		if err != nil {
+			return nil, err
+		}
+		offset = 0
+		if column == sortColumns["id"] {
+			where += fmt.Sprintf(" AND banners.id %s %s", comparison, arg(filter.After.ID))
+		} else {
+			where += fmt.Sprintf(" AND (%s, banners.id) %s (%s, %s)", column, comparison, arg(value), arg(filter.After.ID))
+		}
+	}
+
+	// Запрашиваем на одну строку больше, чтобы понять, есть ли следующая страница
 	query := `
        SELECT banners.id,
               banners.feature_id,
@@ -78,27 +321,39 @@ func (b *bannerRepository) GetBannersByFiltering(ctx context.Context, featureID,
               banners.title,
               banners.text,
               banners.url,
+              banners.content,
               banners.is_active,
+              banners.starts_at,
+              banners.ends_at,
+              banners.timezone,
+              banners.targeting,
+              banners.frequency_cap,
+              banners.version,
+              banners.status,
+              banners.published_version,
+              banners.template_id,
+              banners.template_version,
+              banners.template_variables,
               banners.created_at,
               banners.updated_at,
+              banners.deleted_at,
               array_agg(tags.id) AS tag_ids,
-              array_agg(tags.name) AS tag_names
+              array_agg(tags.name) AS tag_names,
+              ` + rank + ` AS rank,
+              ` + highlight + `
        FROM banners
        LEFT JOIN banner_tags ON banners.id = banner_tags.banner_id
        LEFT JOIN tags ON banner_tags.tag_id = tags.id
-       LEFT JOIN features ON banners.feature_id = features.id
-       WHERE
-           (banners.feature_id = $1 OR $1 IS NULL)
-           AND (tags.id = $2 OR $2 IS NULL)
+       LEFT JOIN features ON banners.feature_id = features.id` + where + `
        GROUP BY
-           banners.id, banners.title, banners.text, banners.url, banners.feature_id, banners.is_active,
-           banners.created_at, banners.updated_at, features.name
-       LIMIT $3 OFFSET $4;
+           banners.id, features.name
+       ORDER BY ` + fmt.Sprintf("%s %s, banners.id %s", column, direction, direction) + `
+       LIMIT ` + arg(filter.Limit+1) + ` OFFSET ` + arg(offset) + `;
    `
 
 	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
 
-	rows, err := b.db.Query(ctx, query, featureID, tagID, limit, offset)
+	rows, err := b.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -106,108 +361,287 @@ func (b *bannerRepository) GetBannersByFiltering(ctx context.Context, featureID,
 
 	for rows.Next() {
 		var ban banner.Banner
-		var tagIDs []int
-		var tagNames []string
+		var tagIDs []*int
+		var tagNames []*string
+		var titleHighlight, textHighlight *string
+
+		err = rows.Scan(&ban.ID, &ban.FeatureID.ID, &ban.FeatureID.Name, &ban.Title, &ban.Text, &ban.URL, &ban.Content, &ban.IsActive,
+			&ban.StartsAt, &ban.EndsAt, &ban.Timezone, &ban.Targeting, &ban.FrequencyCap, &ban.Version, &ban.Status, &ban.PublishedVersion,
+			&ban.TemplateID, &ban.TemplateVersion, &ban.Variables, &ban.CreatedAt, &ban.UpdatedAt, &ban.DeletedAt, &tagIDs, &tagNames,
+			&ban.Rank, &titleHighlight, &textHighlight)
+		if err != nil {
+			return nil, err
+		}
 
-		err = rows.Scan(&ban.ID, &ban.FeatureID.ID, &ban.FeatureID.Name, &ban.Title, &ban.Text, &ban.URL, &ban.IsActive, &ban.CreatedAt, &ban.UpdatedAt, &tagIDs, &tagNames)
+		err = ban.NormalizeSchedule()
 		if err != nil {
 			return nil, err
 		}
 
-		var tags []banner.Tag
+		tags := make([]banner.Tag, 0, len(tagIDs))
 		for i, tagID := range tagIDs {
-			tags = append(tags, banner.Tag{ID: tagID, Name: tagNames[i]})
+			// У баннера без тегов LEFT JOIN дает одну строку с NULL
+			if tagID == nil {
+				continue
+			}
+			tags = append(tags, banner.Tag{ID: *tagID, Name: *tagNames[i]})
 		}
 		ban.Tags = tags
 
-		banners = append(banners, &ban)
+		if titleHighlight != nil && textHighlight != nil {
+			ban.Highlight = &banner.Highlight{Title: *titleHighlight, Text: *textHighlight}
+		}
+
+		page.Banners = append(page.Banners, &ban)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return banners, nil
+	if filter.Limit > 0 && len(page.Banners) > filter.Limit {
+		page.Banners = page.Banners[:filter.Limit]
+		page.NextCursor = banner.NewCursor(page.Banners[filter.Limit-1], filter)
+	}
+
+	return page, nil
 }
 
 func (b *bannerRepository) CreateBanner(ctx context.Context, banner *banner.Banner) error {
-	query := `INSERT INTO banners (title, text, url, is_active, feature_id) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
 
-	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+	err = b.createBannerTx(ctx, tx, banner)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// createBannerTx сохраняет новый баннер с тегами и первой ревизией
+func (b *bannerRepository) createBannerTx(ctx context.Context, tx pgx.Tx, banner *banner.Banner) error {
+	query := `
+		INSERT INTO banners (title, text, url, content, is_active, feature_id, starts_at, ends_at, timezone, targeting, frequency_cap,
+		                     template_id, template_version, template_variables)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, version, status, created_at, updated_at`
 
 	featureID := banner.FeatureID.ID
-	err := b.db.QueryRow(ctx, query, banner.Title, banner.Text, banner.URL, banner.IsActive, featureID).Scan(&banner.ID)
+	err := b.checkConflicts(ctx, tx, 0, featureID, banner.Tags, banner.Targeting != nil)
+	if err != nil {
+		return err
+	}
+
+	// Значения переменных храним только у баннеров, созданных из шаблона
+	var variables any
+	if banner.TemplateID != nil && len(banner.Variables) > 0 {
+		variables = banner.Variables
+	}
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, banner.Title, banner.Text, banner.URL, banner.Content, banner.IsActive, featureID,
+		banner.StartsAt, banner.EndsAt, banner.Timezone, banner.Targeting, banner.FrequencyCap,
+		banner.TemplateID, banner.TemplateVersion, variables).Scan(&banner.ID, &banner.Version, &banner.Status, &banner.CreatedAt, &banner.UpdatedAt)
 	if err != nil {
 		return err
 	}
 
 	// Сохраняем связи баннера с тегами
-	for _, tag := range banner.Tags {
-		q := `INSERT INTO banner_tags (banner_id, tag_id) VALUES ($1, $2)`
-		_, err := b.db.Exec(ctx, q, banner.ID, tag.ID)
-		if err != nil {
-			return err
-		}
+	err = b.replaceBannerTags(ctx, tx, banner.ID, banner.Tags)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	// Первая ревизия баннера
+	err = b.insertRevision(ctx, tx, banner.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	return dbaudit.Record(ctx, tx, audit.ActionCreate, audit.EntityBanner, banner.ID, nil, bannerSnapshot, banner.ID)
 }
 
-func (b *bannerRepository) UpdateBanner(ctx context.Context, banner *banner.Banner) error {
-	query := `UPDATE banners SET title=$1, text=$2, url=$3, is_active=$4, feature_id=$5 WHERE id=$6`
+func (b *bannerRepository) UpdateBanner(ctx context.Context, bn *banner.Banner) error {
 	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
+
+	err = b.updateBannerTx(ctx, tx, bn)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (b *bannerRepository) PatchBanner(ctx context.Context, id int, apply func(current *banner.Banner) error) (*banner.Banner, error) {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем строку, чтобы изменения применялись к актуальному состоянию баннера
+	bn, err := b.getBannerTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = apply(bn)
+	if err != nil {
+		return nil, err
+	}
+	bn.ID = id
+
+	err = b.updateBannerTx(ctx, tx, bn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return bn, nil
+}
+
+// getBannerTx загружает баннер по идентификатору и блокирует его строку до конца транзакции
+func (b *bannerRepository) getBannerTx(ctx context.Context, tx pgx.Tx, id int) (*banner.Banner, error) {
+	query := `
+		SELECT b.id, b.title, b.text, b.url, b.content, b.is_active, b.starts_at, b.ends_at, b.timezone,
+		       b.targeting, b.frequency_cap, b.version, b.status, b.published_version,
+		       b.template_id, b.template_version, b.template_variables, b.created_at, b.updated_at, f.id, f.name
+		FROM banners b
+		JOIN features f ON f.id = b.feature_id
+		WHERE b.id = $1 AND b.deleted_at IS NULL
+		FOR UPDATE OF b`
+
 	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
 
-	featureID := banner.FeatureID.ID
-	_, err = b.db.Exec(ctx, query, banner.Title, banner.Text, banner.URL, banner.IsActive, featureID, banner.ID)
+	var bn banner.Banner
+	err := tx.QueryRow(ctx, query, id).Scan(&bn.ID, &bn.Title, &bn.Text, &bn.URL, &bn.Content, &bn.IsActive,
+		&bn.StartsAt, &bn.EndsAt, &bn.Timezone, &bn.Targeting, &bn.FrequencyCap, &bn.Version, &bn.Status, &bn.PublishedVersion,
+		&bn.TemplateID, &bn.TemplateVersion, &bn.Variables, &bn.CreatedAt, &bn.UpdatedAt, &bn.FeatureID.ID, &bn.FeatureID.Name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, banner.ErrBannerNotFound
+		}
+		return nil, err
+	}
+
+	err = bn.NormalizeSchedule()
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT t.id, t.name
+		FROM banner_tags bt
+		JOIN tags t ON t.id = bt.tag_id
+		WHERE bt.banner_id = $1
+		ORDER BY t.id`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := tx.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bn.Tags = make([]banner.Tag, 0)
+	for rows.Next() {
+		var tag banner.Tag
+		if err = rows.Scan(&tag.ID, &tag.Name); err != nil {
+			return nil, err
+		}
+		bn.Tags = append(bn.Tags, tag)
+	}
+
+	return &bn, rows.Err()
+}
+
+// updateBannerTx сохраняет баннер и записывает его новую ревизию. Новая ревизия
+// становится черновиком: пользователям по-прежнему отдается опубликованная версия.
+func (b *bannerRepository) updateBannerTx(ctx context.Context, tx pgx.Tx, bn *banner.Banner) error {
+	query := `
+		UPDATE banners
+		SET title=$1, text=$2, url=$3, content=$4, is_active=$5, feature_id=$6, starts_at=$7, ends_at=$8, timezone=$9,
+		    targeting=$12, frequency_cap=$13, updated_at=NOW(), version=version+1,
+		    status='draft', reviewed_by=NULL, status_changed_at=NOW()
+		WHERE id=$10 AND deleted_at IS NULL AND ($11::int = 0 OR version=$11)
+		RETURNING version, status, published_version, updated_at`
+
+	featureID := bn.FeatureID.ID
+	err := b.checkConflicts(ctx, tx, bn.ID, featureID, bn.Tags, bn.Targeting != nil)
 	if err != nil {
 		return err
 	}
 
-	// Удаляем все связи баннера с тегами
-	q := `DELETE FROM banner_tags WHERE banner_id=$1`
-	_, err = b.db.Exec(ctx, q, banner.ID)
+	before, err := dbaudit.Snapshot(ctx, tx, bannerSnapshot, bn.ID)
 	if err != nil {
 		return err
 	}
 
-	// Сохраняем новые связи баннера с тегами
-	for _, tag := range banner.Tags {
-		q := `INSERT INTO banner_tags (banner_id, tag_id) VALUES ($1, $2)`
-		_, err := b.db.Exec(ctx, q, banner.ID, tag.ID)
-		if err != nil {
-			return err
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, bn.Title, bn.Text, bn.URL, bn.Content, bn.IsActive, featureID,
+		bn.StartsAt, bn.EndsAt, bn.Timezone, bn.ID, bn.Version, bn.Targeting, bn.FrequencyCap).Scan(&bn.Version, &bn.Status, &bn.PublishedVersion, &bn.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return b.missingOrModified(ctx, tx, bn.ID)
 		}
+		return err
 	}
-	err = tx.Commit(ctx)
+
+	err = b.replaceBannerTags(ctx, tx, bn.ID, bn.Tags)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	err = b.insertRevision(ctx, tx, bn.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	return dbaudit.Record(ctx, tx, audit.ActionUpdate, audit.EntityBanner, bn.ID, before, bannerSnapshot, bn.ID)
 }
 
-func (b *bannerRepository) DeleteBanner(ctx context.Context, id int) error {
-	// Удаляем все связи баннера с тегами
-	q := `DELETE FROM banner_tags WHERE banner_id=$1`
+// DeleteBanner переносит баннер в корзину. Теги и ревизии сохраняются до окончательного
+// удаления, чтобы баннер можно было восстановить.
+func (b *bannerRepository) DeleteBanner(ctx context.Context, id, version int) error {
 	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
-	b.logger.Trace(fmt.Sprintf("SQL Query: %s", q))
 
-	_, err = b.db.Exec(ctx, q, id)
+	before, err := dbaudit.Snapshot(ctx, tx, bannerSnapshot, id)
+	if err != nil {
+		return err
+	}
+
+	// Версия проверяется в том же запросе, что и пометка об удалении
+	query := `UPDATE banners SET deleted_at=NOW() WHERE id=$1 AND deleted_at IS NULL AND ($2::int = 0 OR version=$2)`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	tag, err := tx.Exec(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return b.missingOrModified(ctx, tx, id)
+	}
 
-	// Удаляем сам баннер
-	query := `DELETE FROM banners WHERE id=$1`
-	_, err = b.db.Exec(ctx, query, id)
+	err = dbaudit.Record(ctx, tx, audit.ActionDelete, audit.EntityBanner, id, before, bannerSnapshot, id)
 	if err != nil {
 		return err
 	}
@@ -219,3 +653,544 @@ func (b *bannerRepository) DeleteBanner(ctx context.Context, id int) error {
 
 	return nil
 }
+
+// RestoreBanner возвращает баннер из корзины. Пара (фича, тег) могла быть занята
+// за время нахождения баннера в корзине, поэтому конфликты проверяются заново.
+func (b *bannerRepository) RestoreBanner(ctx context.Context, id int) error {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT b.feature_id, b.targeting IS NOT NULL, b.status = 'archived',
+		       COALESCE((SELECT array_agg(bt.tag_id ORDER BY bt.tag_id) FROM banner_tags bt WHERE bt.banner_id = b.id), '{}')
+		FROM banners b
+		WHERE b.id = $1 AND b.deleted_at IS NOT NULL
+		FOR UPDATE`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var featureID int
+	var targeted, archived bool
+	var tagIDs []int
+	err = tx.QueryRow(ctx, query, id).Scan(&featureID, &targeted, &archived, &tagIDs)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return banner.ErrBannerNotFound
+		}
+		return err
+	}
+
+	tags := make([]banner.Tag, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		tags = append(tags, banner.Tag{ID: tagID})
+	}
+	// Архивный баннер пару не занимает, конфликты проверит unarchive
+	if !archived {
+		err = b.checkConflicts(ctx, tx, id, featureID, tags, targeted)
+		if err != nil {
+			return err
+		}
+	}
+
+	before, err := dbaudit.Snapshot(ctx, tx, bannerSnapshot, id)
+	if err != nil {
+		return err
+	}
+
+	query = `UPDATE banners SET deleted_at=NULL WHERE id=$1`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	// Заголовок баннера мог быть занят новым баннером
+	_, err = tx.Exec(ctx, query, id)
+	if err != nil {
+		return uniqueError(err)
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionRestore, audit.EntityBanner, id, before, bannerSnapshot, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// PurgeDeletedBanners окончательно удаляет до limit баннеров, попавших в корзину раньше before
+func (b *bannerRepository) PurgeDeletedBanners(ctx context.Context, before time.Time, limit int) ([]int, error) {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id
+		FROM banners
+		WHERE deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	ids, err := collectIDs(ctx, tx, query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	err = deleteBanners(ctx, tx, audit.ActionPurge, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// missingOrModified объясняет, почему условный запрос не затронул баннер:
+// его либо нет, либо его версия уже изменилась
+func (b *bannerRepository) missingOrModified(ctx context.Context, tx pgx.Tx, id int) error {
+	query := `SELECT EXISTS (SELECT 1 FROM banners WHERE id=$1 AND deleted_at IS NULL)`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var exists bool
+	err := tx.QueryRow(ctx, query, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return banner.ErrPreconditionFailed
+	}
+	return banner.ErrBannerNotFound
+}
+
+func (b *bannerRepository) GetBannerVersion(ctx context.Context, id int) (int, error) {
+	query := `SELECT version FROM banners WHERE id = $1 AND deleted_at IS NULL`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var version int
+	err := b.db.QueryRow(ctx, query, id).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, banner.ErrBannerNotFound
+		}
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func (b *bannerRepository) GetBannerRevisions(ctx context.Context, bannerID int) ([]*banner.Revision, error) {
+	query := `
+		SELECT r.banner_id, r.version, r.title, r.text, r.url, r.content, r.is_active, r.feature_id, r.tag_ids,
+		       r.starts_at, r.ends_at, r.timezone, r.targeting, r.frequency_cap, r.author_id, r.restored_from, COALESCE(r.version = b.published_version, false), r.created_at
+		FROM banner_revisions r
+		JOIN banners b ON b.id = r.banner_id
+		WHERE r.banner_id = $1 AND b.deleted_at IS NULL
+		ORDER BY r.version DESC`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := b.db.Query(ctx, query, bannerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]*banner.Revision, 0)
+	for rows.Next() {
+		var rev banner.Revision
+		err = rows.Scan(&rev.BannerID, &rev.Version, &rev.Title, &rev.Text, &rev.URL, &rev.Content, &rev.IsActive, &rev.FeatureID, &rev.TagIDs,
+			&rev.StartsAt, &rev.EndsAt, &rev.Timezone, &rev.Targeting, &rev.FrequencyCap, &rev.AuthorID, &rev.RestoredFrom, &rev.IsPublished, &rev.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &rev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// У каждого баннера есть хотя бы одна ревизия
+	if len(revisions) == 0 {
+		return nil, banner.ErrBannerNotFound
+	}
+
+	return revisions, nil
+}
+
+func (b *bannerRepository) RestoreBannerRevision(ctx context.Context, bannerID, version int) (*banner.Revision, error) {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT r.title, r.text, r.url, r.content, r.is_active, r.feature_id, r.tag_ids,
+		       r.starts_at, r.ends_at, r.timezone, r.targeting, r.frequency_cap
+		FROM banner_revisions r
+		JOIN banners b ON b.id = r.banner_id
+		WHERE r.banner_id=$1 AND r.version=$2 AND b.deleted_at IS NULL`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var rev banner.Revision
+	err = tx.QueryRow(ctx, query, bannerID, version).Scan(&rev.Title, &rev.Text, &rev.URL, &rev.Content, &rev.IsActive, &rev.FeatureID, &rev.TagIDs,
+		&rev.StartsAt, &rev.EndsAt, &rev.Timezone, &rev.Targeting, &rev.FrequencyCap)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, banner.ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	err = b.checkRevisionReferences(ctx, tx, rev.FeatureID, rev.TagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]banner.Tag, 0, len(rev.TagIDs))
+	for _, tagID := range rev.TagIDs {
+		tags = append(tags, banner.Tag{ID: tagID})
+	}
+	err = b.checkConflicts(ctx, tx, bannerID, rev.FeatureID, tags, rev.Targeting != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := dbaudit.Snapshot(ctx, tx, bannerSnapshot, bannerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Откат оформляется новой ревизией с состоянием выбранной и, как любое изменение, проходит согласование заново
+	query = `
+		UPDATE banners
+		SET title=$1, text=$2, url=$3, content=$4, is_active=$5, feature_id=$6, starts_at=$7, ends_at=$8, timezone=$9,
+		    targeting=$10, frequency_cap=$11, updated_at=NOW(),
+		    version=version+1, status='draft', reviewed_by=NULL, status_changed_at=NOW()
+		WHERE id=$12
+		RETURNING version`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, rev.Title, rev.Text, rev.URL, rev.Content, rev.IsActive, rev.FeatureID,
+		rev.StartsAt, rev.EndsAt, rev.Timezone, rev.Targeting, rev.FrequencyCap, bannerID).Scan(&rev.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.replaceBannerTags(ctx, tx, bannerID, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.restoreVariantsAndTranslations(ctx, tx, bannerID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.insertRevision(ctx, tx, bannerID, &version)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `SELECT author_id, created_at FROM banner_revisions WHERE banner_id=$1 AND version=$2`
+	err = tx.QueryRow(ctx, query, bannerID, rev.Version).Scan(&rev.AuthorID, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionRestoreRevision, audit.EntityBanner, bannerID, before, bannerSnapshot, bannerID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rev.BannerID = bannerID
+	rev.RestoredFrom = &version
+
+	return &rev, nil
+}
+
+// checkRevisionReferences проверяет, что фича и теги старой ревизии еще существуют:
+// ревизии ссылаются на них без внешних ключей, и удаленные справочники не проверялись при удалении
+func (b *bannerRepository) checkRevisionReferences(ctx context.Context, tx pgx.Tx, featureID int, tagIDs []int) error {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM features WHERE id = $1),
+		       COALESCE((SELECT array_agg(id ORDER BY id) FROM unnest($2::int[]) AS id WHERE id NOT IN (SELECT id FROM tags)), '{}')`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var featureExists bool
+	var missingTags []int
+	err := tx.QueryRow(ctx, query, featureID, tagIDs).Scan(&featureExists, &missingTags)
+	if err != nil {
+		return err
+	}
+
+	var fields []banner.FieldError
+	if !featureExists {
+		fields = append(fields, banner.FieldError{Field: "feature_id.id", Message: fmt.Sprintf("feature %d of the revision no longer exists", featureID)})
+	}
+	for _, tagID := range missingTags {
+		fields = append(fields, banner.FieldError{Field: "tags", Message: fmt.Sprintf("tag %d of the revision no longer exists", tagID)})
+	}
+	if len(fields) > 0 {
+		return &banner.ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func (b *bannerRepository) GetFeatureSchema(ctx context.Context, featureID int) (json.RawMessage, error) {
+	query := `SELECT schema FROM features WHERE id = $1`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var schema json.RawMessage
+	err := b.db.QueryRow(ctx, query, featureID).Scan(&schema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, banner.ErrFeatureNotFound
+		}
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+func (b *bannerRepository) GetBannerURL(ctx context.Context, id int, variantID *int) (string, error) {
+	// Вариант ищется среди вариантов опубликованной ревизии, которые и видел пользователь
+	query := `
+		SELECT COALESCE(NULLIF((SELECT v->>'url' FROM jsonb_array_elements(r.variants) v WHERE (v->>'id')::int = $2::int), ''), r.url)
+		FROM banners b
+		JOIN banner_revisions r ON r.banner_id = b.id AND r.version = b.published_version
+		WHERE b.id = $1 AND b.deleted_at IS NULL`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var url string
+	err := b.db.QueryRow(ctx, query, id, variantID).Scan(&url)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", banner.ErrBannerNotFound
+		}
+		return "", err
+	}
+
+	return url, nil
+}
+
+func (b *bannerRepository) DeleteBannersBatch(ctx context.Context, featureID, tagID *int, limit int) ([]int, error) {
+	query := `
+		SELECT b.id
+		FROM banners b
+		WHERE b.deleted_at IS NULL
+		  AND ($1::int IS NULL OR b.feature_id = $1)
+		  AND ($2::int IS NULL OR EXISTS (SELECT 1 FROM banner_tags bt WHERE bt.banner_id = b.id AND bt.tag_id = $2))
+		ORDER BY b.id
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids, err := collectIDs(ctx, tx, query, featureID, tagID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	before, err := bannerSnapshots(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Массовое удаление тоже переносит баннеры в корзину
+	query = `UPDATE banners SET deleted_at = NOW() WHERE id = ANY($1)`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err = tx.Exec(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordBanners(ctx, tx, audit.ActionDelete, ids, before)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// checkConflicts проверяет, что пары (фича, тег) не заняты другими баннерами: ни их черновиками,
+// ни опубликованными ревизиями, которые пользователи видят до публикации черновика.
+// Блокировка по фиче не дает параллельным транзакциям занять одну пару одновременно.
+func (b *bannerRepository) checkConflicts(ctx context.Context, tx pgx.Tx, bannerID, featureID int, tags []banner.Tag, targeted bool) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, featureID)
+	if err != nil {
+		return err
+	}
+
+	// Баннеры с таргетингом могут делить пару (фича, тег) с другими,
+	// запасной баннер без таргетинга у пары может быть только один
+	if targeted {
+		return nil
+	}
+
+	tagIDs := make([]int, 0, len(tags))
+	for _, tag := range tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	query := `
+		SELECT b.id
+		FROM banners b
+		WHERE b.id <> $3 AND b.deleted_at IS NULL AND b.status <> 'archived' AND (
+		      (b.feature_id = $1 AND b.targeting IS NULL AND EXISTS (
+		          SELECT 1 FROM banner_tags bt WHERE bt.banner_id = b.id AND bt.tag_id = ANY($2)))
+		      OR EXISTS (
+		          SELECT 1 FROM banner_revisions r
+		          WHERE r.banner_id = b.id AND r.version = b.published_version
+		                AND r.feature_id = $1 AND r.targeting IS NULL AND r.tag_ids && $2::int[]))
+		ORDER BY b.id`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	conflictIDs, err := collectIDs(ctx, tx, query, featureID, tagIDs, bannerID)
+	if err != nil {
+		return err
+	}
+
+	if len(conflictIDs) > 0 {
+		return &banner.ConflictError{BannerIDs: conflictIDs}
+	}
+
+	return nil
+}
+
+// replaceBannerTags заменяет набор тегов баннера
+func (b *bannerRepository) replaceBannerTags(ctx context.Context, tx pgx.Tx, bannerID int, tags []banner.Tag) error {
+	q := `DELETE FROM banner_tags WHERE banner_id=$1`
+	_, err := tx.Exec(ctx, q, bannerID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		q := `INSERT INTO banner_tags (banner_id, tag_id) VALUES ($1, $2)`
+		_, err := tx.Exec(ctx, q, bannerID, tag.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreVariantsAndTranslations заменяет варианты и переводы баннера сохраненными в ревизии version.
+// Варианты сохраняют свои идентификаторы, чтобы показы и клики по ним не смешивались с новыми.
+func (b *bannerRepository) restoreVariantsAndTranslations(ctx context.Context, tx pgx.Tx, bannerID, version int) error {
+	q := `DELETE FROM banner_variants WHERE banner_id=$1`
+	_, err := tx.Exec(ctx, q, bannerID)
+	if err != nil {
+		return err
+	}
+
+	q = `
+		INSERT INTO banner_variants (id, banner_id, name, title, text, url, content, weight, created_at, updated_at)
+		SELECT v.id, r.banner_id, v.name, v.title, v.text, v.url, v.content, v.weight, v.created_at, NOW()
+		FROM banner_revisions r, jsonb_populate_recordset(NULL::banner_variants, r.variants) v
+		WHERE r.banner_id=$1 AND r.version=$2`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", q))
+
+	_, err = tx.Exec(ctx, q, bannerID, version)
+	if err != nil {
+		return err
+	}
+
+	q = `DELETE FROM banner_translations WHERE banner_id=$1`
+	_, err = tx.Exec(ctx, q, bannerID)
+	if err != nil {
+		return err
+	}
+
+	q = `
+		INSERT INTO banner_translations (banner_id, locale, title, text, created_at, updated_at)
+		SELECT r.banner_id, t.locale, t.title, t.text, t.created_at, NOW()
+		FROM banner_revisions r, jsonb_populate_recordset(NULL::banner_translations, r.translations) t
+		WHERE r.banner_id=$1 AND r.version=$2`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", q))
+
+	_, err = tx.Exec(ctx, q, bannerID, version)
+	return err
+}
+
+// insertRevision сохраняет снимок текущего состояния баннера вместе с вариантами и переводами как ревизию
+func (b *bannerRepository) insertRevision(ctx context.Context, tx pgx.Tx, bannerID int, restoredFrom *int) error {
+	query := `
+		INSERT INTO banner_revisions (banner_id, version, title, text, url, content, is_active, feature_id, tag_ids,
+		                              starts_at, ends_at, timezone, targeting, frequency_cap, variants, translations, author_id, restored_from)
+		SELECT b.id, b.version, b.title, b.text, b.url, b.content, b.is_active, b.feature_id,
+		       COALESCE((SELECT array_agg(bt.tag_id ORDER BY bt.tag_id) FROM banner_tags bt WHERE bt.banner_id = b.id), '{}'),
+		       b.starts_at, b.ends_at, b.timezone, b.targeting, b.frequency_cap, ` + variantsJSON + `, ` + translationsJSON + `, $2, $3
+		FROM banners b
+		WHERE b.id = $1`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var authorID *int
+	if claims, ok := user.FromContext(ctx); ok {
+		authorID = &claims.ID
+	}
+
+	_, err := tx.Exec(ctx, query, bannerID, authorID, restoredFrom)
+	return err
+}
+
+// bumpVersion оформляет изменение вариантов или переводов новой версией баннера: как и любое
+// изменение содержимого, она возвращает баннер в черновик, а пользователи до публикации видят прежнюю.
+// Архивный баннер остается в архиве, чтобы не обходить проверку пар при разархивации.
+func (b *bannerRepository) bumpVersion(ctx context.Context, tx pgx.Tx, bannerID int) error {
+	query := `
+		UPDATE banners
+		SET version=version+1, updated_at=NOW(), reviewed_by=NULL, status_changed_at=NOW(),
+		    status=CASE WHEN status='archived' THEN status ELSE 'draft' END
+		WHERE id=$1 AND deleted_at IS NULL`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	tag, err := tx.Exec(ctx, query, bannerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return banner.ErrBannerNotFound
+	}
+
+	return b.insertRevision(ctx, tx, bannerID, nil)
+}
@@ -0,0 +1,263 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/db/postgresql"
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolation = "23505"
+
+type tagRepository struct {
+	db     postgresql.Client
+	logger *logging.Logger
+}
+
+func NewTagRepository(db postgresql.Client, logger *logging.Logger) banner.TagStorage {
+	return &tagRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *tagRepository) GetTags(ctx context.Context) ([]banner.Tag, error) {
+	query := `SELECT id, name FROM tags ORDER BY id`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]banner.Tag, 0)
+	for rows.Next() {
+		var tag banner.Tag
+		err = rows.Scan(&tag.ID, &tag.Name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (r *tagRepository) GetTag(ctx context.Context, id int) (*banner.Tag, error) {
+	query := `SELECT id, name FROM tags WHERE id = $1`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var tag banner.Tag
+	err := r.db.QueryRow(ctx, query, id).Scan(&tag.ID, &tag.Name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, banner.ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func (r *tagRepository) CreateTag(ctx context.Context, tag *banner.Tag) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO tags (name) VALUES ($1) RETURNING id`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, tag.Name).Scan(&tag.ID)
+	if err != nil {
+		return uniqueError(err)
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionCreate, audit.EntityTag, tag.ID, nil, tagSnapshot, tag.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *tagRepository) UpdateTag(ctx context.Context, tag *banner.Tag) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, tagSnapshot, tag.ID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return banner.ErrTagNotFound
+	}
+
+	query := `UPDATE tags SET name = $1 WHERE id = $2`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err = tx.Exec(ctx, query, tag.Name, tag.ID)
+	if err != nil {
+		return uniqueError(err)
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionUpdate, audit.EntityTag, tag.ID, before, tagSnapshot, tag.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *tagRepository) DeleteTag(ctx context.Context, id int, cascade bool) ([]int, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Тег используют и баннеры, опубликованная ревизия которых еще показывает его пользователям
+	query := `
+		SELECT b.id FROM banners b
+		WHERE EXISTS (SELECT 1 FROM banner_tags bt WHERE bt.banner_id = b.id AND bt.tag_id = $1)
+		   OR EXISTS (SELECT 1 FROM banner_revisions r WHERE r.banner_id = b.id AND r.version = b.published_version AND $1 = ANY(r.tag_ids))
+		ORDER BY b.id
+		FOR UPDATE`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	bannerIDs, err := collectIDs(ctx, tx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bannerIDs) > 0 {
+		if !cascade {
+			return nil, &banner.InUseError{BannerIDs: bannerIDs}
+		}
+		err = purgeCascade(ctx, tx, bannerIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before, err := dbaudit.Snapshot(ctx, tx, tagSnapshot, id)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, banner.ErrTagNotFound
+	}
+
+	query = `DELETE FROM tags WHERE id = $1`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err = tx.Exec(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbaudit.Write(ctx, tx, &audit.Entry{Action: audit.ActionDelete, Entity: audit.EntityTag, EntityID: id, Before: before})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return bannerIDs, nil
+}
+
+// collectIDs выполняет запрос, возвращающий одну колонку с идентификаторами
+func collectIDs(ctx context.Context, tx pgx.Tx, query string, args ...any) ([]int, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// purgeCascade окончательно удаляет баннеры удаляемого тега или фичи. Действующие баннеры
+// сначала переносятся в корзину, чтобы в журнале было видно их удаление, а затем удаляются вместе с остальными.
+func purgeCascade(ctx context.Context, tx pgx.Tx, ids []int) error {
+	live, err := collectIDs(ctx, tx, `SELECT id FROM banners WHERE id = ANY($1) AND deleted_at IS NULL ORDER BY id`, ids)
+	if err != nil {
+		return err
+	}
+	if len(live) > 0 {
+		before, err := bannerSnapshots(ctx, tx, live)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE banners SET deleted_at = NOW() WHERE id = ANY($1)`, live)
+		if err != nil {
+			return err
+		}
+		err = recordBanners(ctx, tx, audit.ActionDelete, live, before)
+		if err != nil {
+			return err
+		}
+	}
+
+	return deleteBanners(ctx, tx, audit.ActionPurge, ids)
+}
+
+// deleteBanners удаляет баннеры вместе со связями с тегами и записывает action в журнал
+func deleteBanners(ctx context.Context, tx pgx.Tx, action string, ids []int) error {
+	before, err := bannerSnapshots(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM banner_tags WHERE banner_id = ANY($1)`, ids)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM banners WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return err
+	}
+
+	return recordBanners(ctx, tx, action, ids, before)
+}
+
+// uniqueError превращает нарушение уникальности имени в ErrAlreadyExists
+func uniqueError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return banner.ErrAlreadyExists
+	}
+	return err
+}
@@ -0,0 +1,220 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/user"
+	"banner-service/pkg/db/postgresql"
+	"banner-service/pkg/logging"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+type templateRepository struct {
+	db     postgresql.Client
+	logger *logging.Logger
+}
+
+func NewTemplateRepository(db postgresql.Client, logger *logging.Logger) banner.TemplateStorage {
+	return &templateRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// templateVersionQuery выбирает версии шаблонов; время изменения версии - время ее создания
+const templateVersionQuery = `
+	SELECT t.id, t.name, v.title, v.text, v.url, v.content, v.variables, v.version, v.author_id, t.created_at, v.created_at
+	FROM templates t
+	JOIN template_versions v ON v.template_id = t.id
+`
+
+func (r *templateRepository) GetTemplates(ctx context.Context) ([]banner.Template, error) {
+	return r.queryTemplates(ctx, templateVersionQuery+`	WHERE v.version = t.version ORDER BY t.id`)
+}
+
+func (r *templateRepository) GetTemplate(ctx context.Context, id, version int) (*banner.Template, error) {
+	templates, err := r.queryTemplates(ctx, templateVersionQuery+`	WHERE t.id = $1 AND v.version = COALESCE(NULLIF($2::int, 0), t.version)`, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, banner.ErrTemplateNotFound
+	}
+
+	return &templates[0], nil
+}
+
+func (r *templateRepository) GetTemplateVersions(ctx context.Context, id int) ([]banner.Template, error) {
+	templates, err := r.queryTemplates(ctx, templateVersionQuery+`	WHERE t.id = $1 ORDER BY v.version DESC`, id)
+	if err != nil {
+		return nil, err
+	}
+	// У каждого шаблона есть хотя бы одна версия
+	if len(templates) == 0 {
+		return nil, banner.ErrTemplateNotFound
+	}
+
+	return templates, nil
+}
+
+func (r *templateRepository) queryTemplates(ctx context.Context, query string, args ...any) ([]banner.Template, error) {
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]banner.Template, 0)
+	for rows.Next() {
+		var template banner.Template
+		err = rows.Scan(&template.ID, &template.Name, &template.Title, &template.Text, &template.URL, &template.Content, &template.Variables,
+			&template.Version, &template.AuthorID, &template.CreatedAt, &template.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+func (r *templateRepository) CreateTemplate(ctx context.Context, template *banner.Template) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO templates (name, title, text, url, content, variables)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, version, created_at, updated_at`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, template.Name, template.Title, template.Text, template.URL, template.Content, template.Variables).
+		Scan(&template.ID, &template.Version, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return uniqueError(err)
+	}
+
+	err = r.insertTemplateVersion(ctx, tx, template)
+	if err != nil {
+		return err
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionCreate, audit.EntityTemplate, template.ID, nil, templateSnapshot, template.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *templateRepository) UpdateTemplate(ctx context.Context, template *banner.Template) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, templateSnapshot, template.ID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return banner.ErrTemplateNotFound
+	}
+
+	query := `
+		UPDATE templates
+		SET name=$2, title=$3, text=$4, url=$5, content=$6, variables=$7, version=version+1, updated_at=NOW()
+		WHERE id=$1
+		RETURNING version, created_at, updated_at`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, template.ID, template.Name, template.Title, template.Text, template.URL, template.Content, template.Variables).
+		Scan(&template.Version, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return uniqueError(err)
+	}
+
+	err = r.insertTemplateVersion(ctx, tx, template)
+	if err != nil {
+		return err
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionUpdate, audit.EntityTemplate, template.ID, before, templateSnapshot, template.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *templateRepository) DeleteTemplate(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, templateSnapshot, id)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return banner.ErrTemplateNotFound
+	}
+
+	// Баннеры в корзине тоже ссылаются на версию шаблона, из которой созданы
+	query := `SELECT id FROM banners WHERE template_id = $1 ORDER BY id`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	bannerIDs, err := collectIDs(ctx, tx, query, id)
+	if err != nil {
+		return err
+	}
+	if len(bannerIDs) > 0 {
+		return &banner.InUseError{BannerIDs: bannerIDs}
+	}
+
+	query = `DELETE FROM templates WHERE id = $1`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err = tx.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	err = dbaudit.Write(ctx, tx, &audit.Entry{Action: audit.ActionDelete, Entity: audit.EntityTemplate, EntityID: id, Before: before})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertTemplateVersion сохраняет текущее состояние шаблона как его версию
+func (r *templateRepository) insertTemplateVersion(ctx context.Context, tx pgx.Tx, template *banner.Template) error {
+	query := `
+		INSERT INTO template_versions (template_id, version, title, text, url, content, variables, author_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	template.AuthorID = nil
+	if claims, ok := user.FromContext(ctx); ok {
+		template.AuthorID = &claims.ID
+	}
+
+	_, err := tx.Exec(ctx, query, template.ID, template.Version, template.Title, template.Text, template.URL, template.Content,
+		template.Variables, template.AuthorID)
+	return err
+}
@@ -0,0 +1,151 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/banner"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+func (b *bannerRepository) ExportBanners(ctx context.Context, fn func(*banner.Banner) error) error {
+	query := `
+		SELECT b.id, b.title, b.text, b.url, b.content, b.is_active, b.starts_at, b.ends_at, b.timezone, b.targeting, b.frequency_cap,
+		       b.version, b.created_at, b.updated_at, f.id, f.name,
+		       COALESCE(array_agg(t.id ORDER BY t.id) FILTER (WHERE t.id IS NOT NULL), '{}'),
+		       COALESCE(array_agg(t.name ORDER BY t.id) FILTER (WHERE t.id IS NOT NULL), '{}')
+		FROM banners b
+		JOIN features f ON f.id = b.feature_id
+		LEFT JOIN banner_tags bt ON bt.banner_id = b.id
+		LEFT JOIN tags t ON t.id = bt.tag_id
+		WHERE b.deleted_at IS NULL
+		GROUP BY b.id, f.id
+		ORDER BY b.id`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := b.db.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Баннеры передаются в fn по одному, не загружая весь список в память
+	for rows.Next() {
+		var bn banner.Banner
+		var tagIDs []int
+		var tagNames []string
+		err = rows.Scan(&bn.ID, &bn.Title, &bn.Text, &bn.URL, &bn.Content, &bn.IsActive, &bn.StartsAt, &bn.EndsAt, &bn.Timezone, &bn.Targeting, &bn.FrequencyCap,
+			&bn.Version, &bn.CreatedAt, &bn.UpdatedAt, &bn.FeatureID.ID, &bn.FeatureID.Name, &tagIDs, &tagNames)
+		if err != nil {
+			return err
+		}
+		if err = bn.NormalizeSchedule(); err != nil {
+			return err
+		}
+
+		bn.Tags = make([]banner.Tag, 0, len(tagIDs))
+		for i, tagID := range tagIDs {
+			bn.Tags = append(bn.Tags, banner.Tag{ID: tagID, Name: tagNames[i]})
+		}
+
+		if err = fn(&bn); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (b *bannerRepository) GetFeatureAndTagIDs(ctx context.Context) (features, tags map[string]int, err error) {
+	features, err = b.nameIndex(ctx, `SELECT id, name FROM features`)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err = b.nameIndex(ctx, `SELECT id, name FROM tags`)
+	if err != nil {
+		return nil, nil, err
+	}
+	return features, tags, nil
+}
+
+func (b *bannerRepository) nameIndex(ctx context.Context, query string) (map[string]int, error) {
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := b.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var name string
+		if err = rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		index[name] = id
+	}
+
+	return index, rows.Err()
+}
+
+// ImportBanners сохраняет строки импорта без ошибок в одной транзакции. Баннер с тем же
+// заголовком обновляется, иначе создается новый. Транзакция фиксируется, только если
+// commit и ни одна строка не завершилась ошибкой.
+func (b *bannerRepository) ImportBanners(ctx context.Context, items []*banner.ImportItem, commit bool) error {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT id FROM banners WHERE title = $1 AND deleted_at IS NULL FOR UPDATE`
+
+	failed := false
+	for _, item := range items {
+		if len(item.Errors) > 0 {
+			failed = true
+			continue
+		}
+
+		bn := item.Banner
+		b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+		err = tx.QueryRow(ctx, query, bn.Title).Scan(&bn.ID)
+		switch {
+		case err == nil:
+			item.Action = banner.ImportUpdate
+			bn.Version = 0
+			err = b.updateBannerTx(ctx, tx, bn)
+		case errors.Is(err, pgx.ErrNoRows):
+			item.Action = banner.ImportCreate
+			err = b.createBannerTx(ctx, tx, bn)
+		}
+
+		var conflict *banner.ConflictError
+		if errors.As(err, &conflict) {
+			item.ConflictIDs = conflict.BannerIDs
+			item.Errors = append(item.Errors, banner.FieldError{Field: "tags", Message: conflict.Error()})
+			failed = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		item.BannerID = bn.ID
+	}
+
+	if !commit || failed {
+		// Идентификаторы новых баннеров пропадают вместе с откатом
+		for _, item := range items {
+			if item.Action == banner.ImportCreate {
+				item.BannerID = 0
+			}
+		}
+		return nil
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,149 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
+	"banner-service/internal/models/banner"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+func (b *bannerRepository) GetTranslations(ctx context.Context, bannerID int) ([]banner.Translation, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM banners WHERE id = $1 AND deleted_at IS NULL)`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err := b.db.QueryRow(ctx, query, bannerID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, banner.ErrBannerNotFound
+	}
+
+	translations, err := b.getTranslations(ctx, []int{bannerID})
+	if err != nil {
+		return nil, err
+	}
+	if translations[bannerID] == nil {
+		return make([]banner.Translation, 0), nil
+	}
+
+	return translations[bannerID], nil
+}
+
+// getTranslations загружает переводы нескольких баннеров и группирует их по баннеру
+func (b *bannerRepository) getTranslations(ctx context.Context, bannerIDs []int) (map[int][]banner.Translation, error) {
+	query := `
+		SELECT banner_id, locale, title, text, created_at, updated_at
+		FROM banner_translations
+		WHERE banner_id = ANY($1)
+		ORDER BY banner_id, locale`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := b.db.Query(ctx, query, bannerIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := make(map[int][]banner.Translation)
+	for rows.Next() {
+		var t banner.Translation
+		err = rows.Scan(&t.BannerID, &t.Locale, &t.Title, &t.Text, &t.CreatedAt, &t.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		translations[t.BannerID] = append(translations[t.BannerID], t)
+	}
+
+	return translations, rows.Err()
+}
+
+func (b *bannerRepository) SaveTranslation(ctx context.Context, translation *banner.Translation) error {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, translationSnapshot, translation.BannerID, translation.Locale)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO banner_translations (banner_id, locale, title, text)
+		SELECT id, $2, $3, $4 FROM banners WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (banner_id, locale) DO UPDATE
+		SET title = EXCLUDED.title, text = EXCLUDED.text, updated_at = NOW()
+		RETURNING created_at, updated_at`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, translation.BannerID, translation.Locale, translation.Title, translation.Text).
+		Scan(&translation.CreatedAt, &translation.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return banner.ErrBannerNotFound
+		}
+		return err
+	}
+
+	err = b.bumpVersion(ctx, tx, translation.BannerID)
+	if err != nil {
+		return err
+	}
+
+	action := audit.ActionUpdate
+	if before == nil {
+		action = audit.ActionCreate
+	}
+	err = dbaudit.Record(ctx, tx, action, audit.EntityTranslation, translation.BannerID, before, translationSnapshot, translation.BannerID, translation.Locale)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (b *bannerRepository) DeleteTranslation(ctx context.Context, bannerID int, locale string) error {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, translationSnapshot, bannerID, locale)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return banner.ErrTranslationNotFound
+	}
+
+	query := `DELETE FROM banner_translations WHERE banner_id=$1 AND locale=$2`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err = tx.Exec(ctx, query, bannerID, locale)
+	if err != nil {
+		return err
+	}
+
+	err = b.bumpVersion(ctx, tx, bannerID)
+	if err != nil {
+		return err
+	}
+
+	// Перевод определяется баннером и языком; язык есть в снимке before
+	err = dbaudit.Write(ctx, tx, &audit.Entry{Action: audit.ActionDelete, Entity: audit.EntityTranslation, EntityID: bannerID, Before: before})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
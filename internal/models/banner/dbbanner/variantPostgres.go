@@ -0,0 +1,201 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
+	"banner-service/internal/models/banner"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+func (b *bannerRepository) GetBannerSchema(ctx context.Context, bannerID int) (json.RawMessage, error) {
+	query := `
+		SELECT f.schema
+		FROM banners b
+		JOIN features f ON f.id = b.feature_id
+		WHERE b.id = $1 AND b.deleted_at IS NULL`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var schema json.RawMessage
+	err := b.db.QueryRow(ctx, query, bannerID).Scan(&schema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, banner.ErrBannerNotFound
+		}
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+func (b *bannerRepository) GetVariants(ctx context.Context, bannerID int) ([]banner.Variant, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM banners WHERE id = $1 AND deleted_at IS NULL)`
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err := b.db.QueryRow(ctx, query, bannerID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, banner.ErrBannerNotFound
+	}
+
+	variants, err := b.getVariants(ctx, []int{bannerID})
+	if err != nil {
+		return nil, err
+	}
+	if variants[bannerID] == nil {
+		return make([]banner.Variant, 0), nil
+	}
+
+	return variants[bannerID], nil
+}
+
+// getVariants загружает варианты нескольких баннеров и группирует их по баннеру
+func (b *bannerRepository) getVariants(ctx context.Context, bannerIDs []int) (map[int][]banner.Variant, error) {
+	query := `
+		SELECT id, banner_id, name, title, text, url, content, weight, created_at, updated_at
+		FROM banner_variants
+		WHERE banner_id = ANY($1)
+		ORDER BY banner_id, id`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := b.db.Query(ctx, query, bannerIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make(map[int][]banner.Variant)
+	for rows.Next() {
+		var v banner.Variant
+		err = rows.Scan(&v.ID, &v.BannerID, &v.Name, &v.Title, &v.Text, &v.URL, &v.Content, &v.Weight, &v.CreatedAt, &v.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		variants[v.BannerID] = append(variants[v.BannerID], v)
+	}
+
+	return variants, rows.Err()
+}
+
+func (b *bannerRepository) CreateVariant(ctx context.Context, variant *banner.Variant) error {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO banner_variants (banner_id, name, title, text, url, content, weight)
+		SELECT id, $2, $3, $4, $5, $6, $7 FROM banners WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, created_at, updated_at`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, variant.BannerID, variant.Name, variant.Title, variant.Text, variant.URL, variant.Content, variant.Weight).
+		Scan(&variant.ID, &variant.CreatedAt, &variant.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return banner.ErrBannerNotFound
+		}
+		return err
+	}
+
+	err = b.bumpVersion(ctx, tx, variant.BannerID)
+	if err != nil {
+		return err
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionCreate, audit.EntityVariant, variant.ID, nil, variantSnapshot, variant.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (b *bannerRepository) UpdateVariant(ctx context.Context, variant *banner.Variant) error {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, variantSnapshot, variant.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE banner_variants
+		SET name=$3, title=$4, text=$5, url=$6, content=$7, weight=$8, updated_at=NOW()
+		WHERE id=$1 AND banner_id=$2
+		RETURNING created_at, updated_at`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, variant.ID, variant.BannerID, variant.Name, variant.Title, variant.Text, variant.URL, variant.Content, variant.Weight).
+		Scan(&variant.CreatedAt, &variant.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return banner.ErrVariantNotFound
+		}
+		return err
+	}
+
+	err = b.bumpVersion(ctx, tx, variant.BannerID)
+	if err != nil {
+		return err
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionUpdate, audit.EntityVariant, variant.ID, before, variantSnapshot, variant.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (b *bannerRepository) DeleteVariant(ctx context.Context, bannerID, variantID int) error {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, variantSnapshot, variantID)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM banner_variants WHERE id=$1 AND banner_id=$2`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	tag, err := tx.Exec(ctx, query, variantID, bannerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return banner.ErrVariantNotFound
+	}
+
+	err = b.bumpVersion(ctx, tx, bannerID)
+	if err != nil {
+		return err
+	}
+
+	err = dbaudit.Write(ctx, tx, &audit.Entry{Action: audit.ActionDelete, Entity: audit.EntityVariant, EntityID: variantID, Before: before})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
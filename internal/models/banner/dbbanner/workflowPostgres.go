@@ -0,0 +1,99 @@
+package dbbanner
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
+	"banner-service/internal/models/banner"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+func (b *bannerRepository) ChangeStatus(ctx context.Context, id, version int, apply func(state *banner.WorkflowState) error) (*banner.WorkflowState, error) {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Автор берется из ревизии текущей версии: рецензент решает именно по ней
+	query := `
+		SELECT b.id, b.version, b.status, b.published_version, r.author_id, b.reviewed_by, b.status_changed_at
+		FROM banners b
+		JOIN banner_revisions r ON r.banner_id = b.id AND r.version = b.version
+		WHERE b.id = $1 AND b.deleted_at IS NULL
+		FOR UPDATE OF b`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var state banner.WorkflowState
+	err = tx.QueryRow(ctx, query, id).Scan(&state.BannerID, &state.Version, &state.Status, &state.PublishedVersion,
+		&state.AuthorID, &state.ReviewedBy, &state.StatusChangedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, banner.ErrBannerNotFound
+		}
+		return nil, err
+	}
+	if version != 0 && state.Version != version {
+		return nil, banner.ErrPreconditionFailed
+	}
+
+	previous := state.Status
+	err = apply(&state)
+	if err != nil {
+		return nil, err
+	}
+
+	// Пока баннер был в архиве, его пару (фича, тег) мог занять другой баннер. Публикуемая версия
+	// проверяется заново: с момента ее сохранения пары могли занять опубликованные ревизии других баннеров.
+	unarchived := previous == banner.StatusArchived && state.Status != banner.StatusArchived
+	if unarchived || state.Status == banner.StatusPublished {
+		bn, err := b.getBannerTx(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		err = b.checkConflicts(ctx, tx, id, bn.FeatureID.ID, bn.Tags, bn.Targeting != nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before, err := dbaudit.Snapshot(ctx, tx, bannerSnapshot, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch state.Status {
+	case banner.StatusPublished:
+		state.PublishedVersion = &state.Version
+	case banner.StatusArchived:
+		state.PublishedVersion = nil
+	}
+
+	query = `
+		UPDATE banners
+		SET status=$2, published_version=$3, reviewed_by=$4, status_changed_at=NOW()
+		WHERE id=$1
+		RETURNING status_changed_at`
+
+	b.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	err = tx.QueryRow(ctx, query, id, state.Status, state.PublishedVersion, state.ReviewedBy).Scan(&state.StatusChangedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbaudit.Record(ctx, tx, audit.ActionChangeStatus, audit.EntityBanner, id, before, bannerSnapshot, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
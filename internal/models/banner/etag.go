@@ -0,0 +1,131 @@
+package banner
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETag формирует сильный entity tag баннера из номера его версии
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch возвращает версии баннера из списка entity tags заголовка If-Match.
+// Отсутствующий заголовок и "*" дают nil, то есть запись без проверки версии.
+// Слабые и чужие entity tags не могут совпасть с версией баннера при сильном сравнении
+// и пропускаются; если в списке не осталось ни одной версии, возвращается ErrPreconditionFailed.
+func parseIfMatch(r *http.Request) ([]int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		return nil, nil
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, nil
+		}
+		if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil || version <= 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, ErrPreconditionFailed
+	}
+	return versions, nil
+}
+
+// ifMatchVersion возвращает версию, которую должен иметь баннер id для записи по заголовку If-Match;
+// 0 - запись без проверки. Из списка выбирается текущая версия баннера, если она в нем есть.
+// Запись все равно сверяет версию атомарно, поэтому изменение баннера после чтения даст 412.
+func (h *Handler) ifMatchVersion(ctx context.Context, r *http.Request, id int) (int, error) {
+	versions, err := parseIfMatch(r)
+	if err != nil || len(versions) == 0 {
+		return 0, err
+	}
+	if len(versions) == 1 {
+		return versions[0], nil
+	}
+
+	current, err := h.repository.GetBannerVersion(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if !containsVersion(versions, current) {
+		return 0, ErrPreconditionFailed
+	}
+	return current, nil
+}
+
+func containsVersion(versions []int, version int) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// userBannerETag учитывает идентификатор баннера: для пары (фича, тег) может начать
+// отдаваться другой баннер с тем же номером версии. Язык и вариант A/B-теста различают
+// представления одной версии. clickURL - подписанная ссылка перехода из ответа с tracking=true:
+// она зависит от пользователя и срока действия, поэтому тоже входит в entity tag,
+// и клиент не получит 304 для копии с чужой или истекающей ссылкой.
+func userBannerETag(banner *Banner, clickURL string) string {
+	tag := strconv.Itoa(banner.ID) + "-" + strconv.Itoa(banner.Version)
+	if banner.Locale != "" {
+		tag += "-" + banner.Locale
+	}
+	if banner.VariantID != nil {
+		tag += "-" + strconv.Itoa(*banner.VariantID)
+	}
+	if clickURL != "" {
+		hash := fnv.New32a()
+		hash.Write([]byte(clickURL))
+		tag += "-t" + strconv.FormatUint(uint64(hash.Sum32()), 36)
+	}
+	return `"` + tag + `"`
+}
+
+// writeNotModified выставляет ETag и Last-Modified и отвечает 304, если у клиента
+// уже есть актуальная версия. If-Modified-Since учитывается только без If-None-Match.
+func writeNotModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	modified = modified.UTC().Truncate(time.Second)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		notModified = etagListMatches(inm, etag)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		notModified = err == nil && !modified.After(since)
+	}
+	if !notModified {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagListMatches сравнивает список из If-None-Match с etag (слабое сравнение)
+func etagListMatches(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
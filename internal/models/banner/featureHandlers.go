@@ -0,0 +1,157 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"banner-service/pkg/logging"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+type FeatureHandler struct {
+	logger     *logging.Logger
+	repository FeatureStorage
+	// Cache - кэш баннеров, из которого убираются баннеры, удаленные вместе с фичей
+	Cache *CacheBanner
+}
+
+func NewFeatureHandler(repository FeatureStorage, logger *logging.Logger) *FeatureHandler {
+	return &FeatureHandler{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (h *FeatureHandler) GetFeatures(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	features, err := h.repository.GetFeatures(ctx)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, features)
+}
+
+func (h *FeatureHandler) GetFeature(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid feature ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	feature, err := h.repository.GetFeature(ctx, id)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, feature)
+}
+
+func (h *FeatureHandler) CreateFeature(w http.ResponseWriter, r *http.Request) {
+	var feature Feature
+	err := json.NewDecoder(r.Body).Decode(&feature)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = validateFeature(&feature)
+	if err == nil {
+		err = h.repository.CreateFeature(ctx, &feature)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, feature)
+}
+
+func (h *FeatureHandler) UpdateFeature(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid feature ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	var feature Feature
+	err = json.NewDecoder(r.Body).Decode(&feature)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	feature.ID = id
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = validateFeature(&feature)
+	if err == nil {
+		err = h.repository.UpdateFeature(ctx, &feature)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, feature)
+}
+
+func (h *FeatureHandler) DeleteFeature(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid feature ID parameter", http.StatusBadRequest)
+		return
+	}
+	// cascade=true окончательно удаляет и все баннеры этой фичи
+	cascade, err := strconv.ParseBool(r.URL.Query().Get("cascade"))
+	if err != nil {
+		cascade = false
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	deleted, err := h.repository.DeleteFeature(ctx, id, cascade)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	if h.Cache != nil && len(deleted) > 0 {
+		h.Cache.DeleteBannersByID(ctx, deleted)
+	}
+
+	response := map[string]string{"message": fmt.Sprintf("feature with ID (id %d) deleted", id)}
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+// validateFeature проверяет имя фичи и компилируемость ее JSON Schema
+func validateFeature(feature *Feature) error {
+	err := validateName(feature.Name)
+	if err != nil {
+		return err
+	}
+	if string(feature.Schema) == "null" {
+		feature.Schema = nil
+	}
+	if feature.Schema != nil {
+		if _, err := compileSchema(feature.Schema); err != nil {
+			return &ValidationError{Fields: []FieldError{{Field: "schema", Message: err.Error()}}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package banner
+
+import (
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FrequencyCap ограничивает число показов баннера одному пользователю за окно Window.
+// Когда лимит исчерпан, отдается баннер FallbackBannerID, а если он не задан - 404.
+type FrequencyCap struct {
+	Count            int      `json:"count"`
+	Window           Duration `json:"window"`
+	FallbackBannerID *int     `json:"fallback_banner_id,omitempty"`
+}
+
+// Duration - длительность, которая в JSON записывается строкой вида "24h" или "30m"
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"24h\"")
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// FrequencyStore хранит счетчики показов пользователям. Реализация по умолчанию
+// работает в памяти процесса; при нескольких экземплярах сервиса нужно общее хранилище.
+type FrequencyStore interface {
+	// Allow атомарно увеличивает счетчик key, если он меньше limit, и сообщает, разрешен ли показ.
+	// Окно отсчитывается от первого показа и сбрасывается по истечении window.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
+
+// FrequencyCounterStorage - общее хранилище счетчиков, из которого истекшие счетчики
+// удаляются фоновой задачей, а не при следующем показе
+type FrequencyCounterStorage interface {
+	FrequencyStore
+	// DeleteExpired удаляет счетчики, окно которых уже закончилось, и возвращает их число
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func (c *FrequencyCap) validate(bannerID int) []FieldError {
+	if c == nil {
+		return nil
+	}
+	var fields []FieldError
+	if c.Count <= 0 {
+		fields = append(fields, FieldError{Field: "frequency_cap.count", Message: "must be greater than 0"})
+	}
+	if c.Window.Duration < time.Second {
+		fields = append(fields, FieldError{Field: "frequency_cap.window", Message: "must be at least 1s"})
+	}
+	if c.FallbackBannerID != nil && *c.FallbackBannerID == bannerID {
+		fields = append(fields, FieldError{Field: "frequency_cap.fallback_banner_id", Message: "must differ from the banner itself"})
+	}
+	return fields
+}
+
+func frequencyKey(bannerID, userID int) string {
+	return strconv.Itoa(bannerID) + ":" + strconv.Itoa(userID)
+}
+
+// applyFrequencyCap учитывает показ баннера пользователю. Если лимит исчерпан,
+// возвращается запасной баннер из настроек или nil.
+func (h *Handler) applyFrequencyCap(ctx context.Context, banner *Banner, userID int, locale string) (*Banner, error) {
+	limit := banner.FrequencyCap
+	if limit == nil || h.Frequency == nil {
+		return banner, nil
+	}
+
+	allowed, err := h.Frequency.Allow(ctx, frequencyKey(banner.ID, userID), limit.Count, limit.Window.Duration)
+	if err != nil || allowed {
+		return banner, err
+	}
+	if limit.FallbackBannerID == nil {
+		return nil, nil
+	}
+
+	// Запасной баннер кэшируется так же, как кандидаты пары (фича, тег)
+	key := fmt.Sprintf("id-%d-%s", *limit.FallbackBannerID, locale)
+	fallbacks, ok := h.Cache.GetBanners(ctx, key)
+	if !ok {
+		fallback, err := h.repository.GetPublishedBanner(ctx, *limit.FallbackBannerID)
+		if err != nil {
+			if errors.Is(err, ErrBannerNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		fallbacks = []*Banner{fallback.localize(localeChain(locale, h.Locales))}
+		h.Cache.SetBanners(ctx, key, fallbacks)
+	}
+	if !fallbacks[0].IsActiveAt(time.Now()) {
+		return nil, nil
+	}
+	return fallbacks[0], nil
+}
+
+// MemoryFrequencyStore - счетчики показов в памяти процесса
+type MemoryFrequencyStore struct {
+	counters map[string]*frequencyCounter
+	mutex    sync.Mutex
+	quit     chan struct{}
+}
+
+type frequencyCounter struct {
+	count   int
+	resetAt time.Time
+}
+
+func NewMemoryFrequencyStore() *MemoryFrequencyStore {
+	store := &MemoryFrequencyStore{
+		counters: make(map[string]*frequencyCounter),
+		quit:     make(chan struct{}),
+	}
+	go store.runExpirationLoop()
+	return store
+}
+
+func (s *MemoryFrequencyStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.resetAt) {
+		counter = &frequencyCounter{resetAt: now.Add(window)}
+		s.counters[key] = counter
+	}
+	if counter.count >= limit {
+		return false, nil
+	}
+	counter.count++
+	return true, nil
+}
+
+func (s *MemoryFrequencyStore) runExpirationLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.expire()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *MemoryFrequencyStore) expire() {
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, counter := range s.counters {
+		if !now.Before(counter.resetAt) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+func (s *MemoryFrequencyStore) Close() {
+	close(s.quit)
+}
+
+// FrequencyCleaner периодически удаляет из общего хранилища счетчики лимита показов
+// с закончившимся окном. Счетчики в памяти процесса удаляются самим MemoryFrequencyStore.
+type FrequencyCleaner struct {
+	counters FrequencyCounterStorage
+	logger   *logging.Logger
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewFrequencyCleaner(counters FrequencyCounterStorage, logger *logging.Logger, interval time.Duration) *FrequencyCleaner {
+	return &FrequencyCleaner{
+		counters: counters,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+func (c *FrequencyCleaner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			c.deleteExpired(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (c *FrequencyCleaner) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.wg.Wait()
+}
+
+func (c *FrequencyCleaner) deleteExpired(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	deleted, err := c.counters.DeleteExpired(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.Errorf("frequency counters cleanup failed: %v", err)
+		}
+		return
+	}
+	if deleted > 0 {
+		c.logger.Infof("deleted %d expired frequency counters", deleted)
+	}
+}
@@ -1,6 +1,8 @@
 package banner
 
 import (
+	"banner-service/internal/models/impression"
+	"banner-service/internal/models/job"
 	"banner-service/internal/models/user"
 	"banner-service/internal/utils"
 	"banner-service/pkg/logging"
@@ -9,18 +11,32 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
 type Handler struct {
-	logger     *logging.Logger
-	repository Storage
-	Cache      *CacheBanner
+	logger      *logging.Logger
+	repository  Storage
+	Cache       *CacheBanner
+	Jobs        job.Storage
+	Impressions *impression.Tracker
+	Clicks      impression.Storage
+	Links       *LinkSigner
+	Frequency   FrequencyStore
+	Templates   TemplateStorage
+	// Locales - поддерживаемые языки баннеров; первый используется по умолчанию,
+	// остальные в указанном порядке служат запасными, если перевода нет
+	Locales []string
 }
 
 func NewHandler(repository Storage, logger *logging.Logger, cache *CacheBanner) *Handler {
@@ -47,7 +63,6 @@ func (h *Handler) GetUserBanner(w http.ResponseWriter, r *http.Request, adminChe
 	tagIDStr := r.URL.Query().Get("tag_id")
 	featureIDStr := r.URL.Query().Get("feature_id")
 	useLastRevisionStr := r.URL.Query().Get("use_last_revision")
-	keyCache := fmt.Sprintf("%s-%s", tagIDStr, featureIDStr)
 
 	tagID, err := strconv.Atoi(tagIDStr)
 	if err != nil {
@@ -59,23 +74,26 @@ func (h *Handler) GetUserBanner(w http.ResponseWriter, r *http.Request, adminChe
 		http.Error(w, "Invalid feature_id parameter", http.StatusBadRequest)
 		return
 	}
-	useLastRevision, err := strconv.ParseBool(useLastRevisionStr)
-	if err != nil {
-		useLastRevision = false
-	}
+	useLastRevision, _ := strconv.ParseBool(useLastRevisionStr)
+	// Неопубликованные версии доступны только администраторам для предпросмотра
+	useLastRevision = useLastRevision && isAdmin
+
+	// Кандидаты кэшируются отдельно для каждого языка уже с примененными переводами
+	locale := NegotiateLocale(r, h.Locales)
+	keyCache := userBannerCacheKey(Placement{FeatureID: featureID, TagID: tagID}, locale)
 
-	ctx, cancel := getContextTimeout()
+	ctx, cancel := getContextTimeout(r.Context())
 	defer cancel()
 
-	var banner *Banner
+	var candidates []*Banner
 	var ok bool
 
 	if useLastRevision {
-		banner, err = h.repository.GetBannerFromDB(ctx, tagID, featureID, useLastRevision)
+		candidates, err = h.repository.GetBannerCandidates(ctx, tagID, featureID, useLastRevision)
 	} else {
-		banner, ok = h.Cache.GetBanner(ctx, keyCache)
+		candidates, ok = h.Cache.GetBanners(ctx, keyCache)
 		if !ok {
-			banner, err = h.repository.GetBannerFromDB(ctx, tagID, featureID, useLastRevision)
+			candidates, err = h.repository.GetBannerCandidates(ctx, tagID, featureID, useLastRevision)
 		}
 	}
 
@@ -83,26 +101,262 @@ func (h *Handler) GetUserBanner(w http.ResponseWriter, r *http.Request, adminChe
 		handleErrors(err, h.logger, w)
 		return
 	}
+	if !ok {
+		candidates = localizeBanners(candidates, localeChain(locale, h.Locales))
+		// В кэше хранятся все опубликованные кандидаты, баннер по таргетингу выбирается для каждого пользователя.
+		// Запись обновляется только при промахе, иначе частые запросы продлевали бы ее бесконечно.
+		if !useLastRevision {
+			h.Cache.SetBanners(ctx, keyCache, candidates)
+		}
+	}
 
-	if banner == nil || (!isAdmin && !banner.IsActive) {
+	banner, err := h.showBanner(ctx, r, candidates, isAdmin, locale)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	if banner == nil {
 		http.Error(w, "Banner not available", http.StatusNotFound)
 		return
 	}
 
-	h.Cache.SetBanner(ctx, keyCache, banner)
-	utils.RespondJSON(w, http.StatusOK, banner)
+	w.Header().Add("Vary", "Accept-Language")
+	if banner.Locale != "" {
+		w.Header().Set("Content-Language", banner.Locale)
+	}
+
+	// Ответ 304 тоже означает показ: клиент отображает сохраненную копию баннера
+	h.trackImpression(r, banner, featureID, tagID)
+	shown := h.withClickURL(r, banner, featureID, tagID)
+	etag, modified := userBannerETag(banner, ""), banner.UpdatedAt
+	if shown != banner {
+		// Ответ с новой ссылкой перехода изменился не раньше начала периода ее выдачи
+		etag = userBannerETag(banner, shown.URL)
+		if period := h.Links.period(time.Now()); period.After(modified) {
+			modified = period
+		}
+	}
+	if writeNotModified(w, r, etag, modified) {
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, shown)
+}
+
+func userBannerCacheKey(placement Placement, locale string) string {
+	return fmt.Sprintf("%d-%d-%s", placement.TagID, placement.FeatureID, locale)
+}
+
+// invalidatePublished убирает из кэша кандидатов тех пар (фича, тег), на которых показывается
+// опубликованная ревизия баннера. Баннер без опубликованной ревизии пользователям не виден.
+func (h *Handler) invalidatePublished(ctx context.Context, id int) {
+	published, err := h.repository.GetPublishedBanner(ctx, id)
+	if err != nil {
+		if !errors.Is(err, ErrBannerNotFound) {
+			h.logger.Error(err)
+		}
+		return
+	}
+
+	placements := make([]Placement, 0, len(published.Tags))
+	for _, tag := range published.Tags {
+		placements = append(placements, Placement{FeatureID: published.FeatureID.ID, TagID: tag.ID})
+	}
+	h.Cache.DeletePlacements(ctx, placements)
+}
+
+// showBanner выбирает баннер для пользователя из кандидатов пары (фича, тег) и применяет
+// к нему лимит показов и A/B-тест. nil означает, что показать нечего.
+func (h *Handler) showBanner(ctx context.Context, r *http.Request, candidates []*Banner, isAdmin bool, locale string) (*Banner, error) {
+	now := time.Now()
+	banner := SelectBanner(candidates, AudienceFromRequest(r), func(b *Banner) bool {
+		return isAdmin || b.IsActiveAt(now)
+	})
+	if banner == nil {
+		return nil, nil
+	}
+
+	claims, hasUser := user.FromContext(r.Context())
+	// Лимит показов не применяется к администраторам, которые проверяют баннеры
+	if hasUser && !isAdmin {
+		var err error
+		banner, err = h.applyFrequencyCap(ctx, banner, claims.ID, locale)
+		if err != nil || banner == nil {
+			return nil, err
+		}
+	}
+
+	// Пользователь получает вариант A/B-теста, закрепленный за ним по хэшу идентификатора.
+	// Поля варианта написаны на основном языке баннера, поэтому перевод применяется поверх них заново.
+	if hasUser && len(banner.Variants) > 0 {
+		if variant := pickVariant(banner.Variants, banner.ID, claims.ID); variant != nil {
+			banner = banner.withVariant(variant).localize(localeChain(locale, h.Locales))
+		}
+	}
+	return banner, nil
+}
+
+// withClickURL по запросу клиента отдает вместо URL баннера подписанную ссылку перехода.
+// Баннер из кэша не изменяем, подменяем URL в копии.
+func (h *Handler) withClickURL(r *http.Request, banner *Banner, featureID, tagID int) *Banner {
+	tracking, _ := strconv.ParseBool(r.URL.Query().Get("tracking"))
+	if !tracking || h.Links == nil || banner.URL == "" {
+		return banner
+	}
+	var userID *int
+	if claims, ok := user.FromContext(r.Context()); ok {
+		userID = &claims.ID
+	}
+	tracked := *banner
+	tracked.URL = h.Links.ClickURL(banner.ID, featureID, tagID, banner.VariantID, userID)
+	return &tracked
+}
+
+// trackImpression ставит показ баннера в очередь на запись, не дожидаясь базы данных
+func (h *Handler) trackImpression(r *http.Request, banner *Banner, featureID, tagID int) {
+	if h.Impressions == nil {
+		return
+	}
+	event := impression.Impression{
+		BannerID:  banner.ID,
+		FeatureID: featureID,
+		TagID:     tagID,
+		VariantID: banner.VariantID,
+		ShownAt:   time.Now(),
+	}
+	if claims, ok := user.FromContext(r.Context()); ok {
+		event.UserID = &claims.ID
+	}
+	h.Impressions.Track(event)
 }
 
 func handleErrors(err error, logger *logging.Logger, w http.ResponseWriter) {
+	var conflict *ConflictError
+	var inUse *InUseError
+	var transition *TransitionError
+	var validationErr *ValidationError
 	if errors.Is(err, context.DeadlineExceeded) {
 		logger.Error(err)
 		http.Error(w, "request timeout", http.StatusRequestTimeout)
+	} else if errors.Is(err, ErrBannerNotFound) || errors.Is(err, ErrRevisionNotFound) || errors.Is(err, ErrVariantNotFound) ||
+		errors.Is(err, ErrTranslationNotFound) || errors.Is(err, ErrTagNotFound) || errors.Is(err, ErrFeatureNotFound) ||
+		errors.Is(err, ErrTemplateNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.As(err, &conflict) {
+		respondConflict(w, conflict)
+	} else if errors.As(err, &inUse) {
+		response := map[string]interface{}{
+			"message":    inUse.Error(),
+			"banner_ids": inUse.BannerIDs,
+		}
+		utils.RespondJSON(w, http.StatusConflict, response)
+	} else if errors.Is(err, ErrAlreadyExists) || errors.As(err, &transition) {
+		http.Error(w, err.Error(), http.StatusConflict)
+	} else if errors.Is(err, ErrSelfReview) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+	} else if errors.Is(err, ErrPreconditionFailed) {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	} else if errors.As(err, &validationErr) {
+		utils.RespondJSON(w, http.StatusBadRequest, validationErr)
 	} else {
 		logger.Error(err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 	}
 }
 
+func respondConflict(w http.ResponseWriter, conflict *ConflictError) {
+	response := map[string]interface{}{
+		"message":    conflict.Error(),
+		"banner_ids": conflict.BannerIDs,
+	}
+	utils.RespondJSON(w, http.StatusConflict, response)
+}
+
+// GetBanners отдает список баннеров с необязательными фильтрами feature_id и tag_id
+func (h *Handler) GetBanners(w http.ResponseWriter, r *http.Request) {
+	h.getBanners(w, r, false)
+}
+
+func (h *Handler) getBanners(w http.ResponseWriter, r *http.Request, deleted bool) {
+	filter := Filter{Deleted: deleted}
+	var err error
+
+	query := r.URL.Query()
+	filter.FeatureID, err = utils.ParseOptionalInt(query.Get("feature_id"))
+	if err != nil {
+		http.Error(w, "Invalid feature_id parameter", http.StatusBadRequest)
+		return
+	}
+	filter.TagID, err = utils.ParseOptionalInt(query.Get("tag_id"))
+	if err != nil {
+		http.Error(w, "Invalid tag_id parameter", http.StatusBadRequest)
+		return
+	}
+	if status := query.Get("status"); status != "" {
+		if !validStatus(status) {
+			http.Error(w, "Invalid status parameter", http.StatusBadRequest)
+			return
+		}
+		filter.Status = &status
+	}
+
+	filter.Limit = defaultLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		filter.Limit, err = strconv.Atoi(limitStr)
+		if err != nil || filter.Limit <= 0 {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		filter.Offset, err = strconv.Atoi(offsetStr)
+		if err != nil || filter.Offset < 0 {
+			http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter.Query = strings.TrimSpace(query.Get("q"))
+	filter.Highlight, err = strconv.ParseBool(query.Get("highlight"))
+	if err != nil {
+		filter.Highlight = false
+	}
+
+	// Результаты поиска по умолчанию упорядочены по релевантности
+	sort := query.Get("sort")
+	if sort == "" && filter.Query != "" {
+		sort = "relevance"
+	}
+	filter.Sort, filter.Desc, err = ParseSort(sort)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Sort == "relevance" && filter.Query == "" {
+		http.Error(w, "sort by relevance requires q parameter", http.StatusBadRequest)
+		return
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		filter.After, err = DecodeCursor(cursor, filter.Sort, filter.Desc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, ok := h.listBanners(w, r, filter)
+	if !ok {
+		return
+	}
+
+	response := map[string]interface{}{"items": page.Banners}
+	if page.NextCursor != nil {
+		response["next_cursor"] = page.NextCursor.Encode()
+	}
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+// GetBannerFilter - устаревший вариант GetBanners с параметрами в пути
 func (h *Handler) GetBannerFilter(w http.ResponseWriter, r *http.Request) {
 	tagIDStr := chi.URLParam(r, "tag_id")
 	featureIDStr := chi.URLParam(r, "feature_id")
@@ -120,32 +374,44 @@ func (h *Handler) GetBannerFilter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
+	if err != nil || limit <= 0 {
 		http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
 		return
 	}
 	offset, err := strconv.Atoi(offsetStr)
-	if err != nil {
+	if err != nil || offset < 0 {
 		http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
 		return
 	}
 
-	ctx, cancel := getContextTimeout()
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", fmt.Sprintf("</banner?feature_id=%d&tag_id=%d&limit=%d&offset=%d>; rel=\"successor-version\"", featureID, tagID, limit, offset))
+
+	page, ok := h.listBanners(w, r, Filter{FeatureID: &featureID, TagID: &tagID, Limit: limit, Offset: offset, Sort: "id"})
+	if !ok {
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, page.Banners)
+}
+
+// listBanners загружает страницу баннеров и выставляет X-Total-Count. При ошибке ответ уже отправлен.
+func (h *Handler) listBanners(w http.ResponseWriter, r *http.Request, filter Filter) (*Page, bool) {
+	if filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
 	defer cancel()
 
-	banners, err := h.repository.GetBannersByFiltering(ctx, featureID, tagID, limit, offset)
+	page, err := h.repository.GetBannersByFiltering(ctx, filter)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			h.logger.Error(err)
-			http.Error(w, "request timeout", http.StatusRequestTimeout)
-			return
-		}
-		h.logger.Error(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		handleErrors(err, h.logger, w)
+		return nil, false
 	}
 
-	utils.RespondJSON(w, http.StatusOK, banners)
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	return page, true
 }
 
 func (h *Handler) CreateBannerHandler(w http.ResponseWriter, r *http.Request) {
@@ -157,28 +423,35 @@ func (h *Handler) CreateBannerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = validateBanner(banner)
+	err = normalizeBanner(&banner)
 	if err != nil {
-		h.logger.Error(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		handleErrors(err, h.logger, w)
 		return
 	}
 
-	ctx, cancel := getContextTimeout()
+	ctx, cancel := getContextTimeout(r.Context())
 	defer cancel()
 
+	if banner.TemplateID != nil {
+		err = h.applyTemplate(ctx, &banner)
+	} else if banner.TemplateVersion != nil || len(banner.Variables) > 0 {
+		err = &ValidationError{Fields: []FieldError{{Field: "template_id", Message: "is required with template_version and variables"}}}
+	}
+	if err == nil {
+		err = h.validateBanner(ctx, banner)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
 	err = h.repository.CreateBanner(ctx, &banner)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			h.logger.Error(err)
-			http.Error(w, "request timeout", http.StatusRequestTimeout)
-			return
-		}
-		h.logger.Error(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handleErrors(err, h.logger, w)
 		return
 	}
 
+	w.Header().Set("ETag", ETag(banner.Version))
 	utils.RespondJSON(w, http.StatusCreated, banner)
 }
 
@@ -197,28 +470,35 @@ func (h *Handler) UpdateBannerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	banner.ID = id
-	err = validateBanner(banner)
+	err = normalizeBanner(&banner)
 	if err != nil {
-		h.logger.Error(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		handleErrors(err, h.logger, w)
 		return
 	}
 
-	ctx, cancel := getContextTimeout()
+	ctx, cancel := getContextTimeout(r.Context())
 	defer cancel()
 
+	// Версия из тела запроса игнорируется, проверяется только If-Match
+	banner.Version, err = h.ifMatchVersion(ctx, r, id)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	err = h.validateBanner(ctx, banner)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
 	err = h.repository.UpdateBanner(ctx, &banner)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			h.logger.Error(err)
-			http.Error(w, "request timeout", http.StatusRequestTimeout)
-			return
-		}
-		h.logger.Error(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handleErrors(err, h.logger, w)
 		return
 	}
 
+	w.Header().Set("ETag", ETag(banner.Version))
 	utils.RespondJSON(w, http.StatusCreated, banner)
 }
 
@@ -231,36 +511,73 @@ func (h *Handler) DeleteBannerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := getContextTimeout()
+	ctx, cancel := getContextTimeout(r.Context())
 	defer cancel()
 
-	err = h.repository.DeleteBanner(ctx, id)
+	version, err := h.ifMatchVersion(ctx, r, id)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			h.logger.Error(err.Error())
-			http.Error(w, "request timeout", http.StatusRequestTimeout)
-			return
-		}
-		h.logger.Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handleErrors(err, h.logger, w)
 		return
 	}
 
+	err = h.repository.DeleteBanner(ctx, id, version)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	// Баннер из корзины не должен отдаваться пользователям до истечения TTL кэша
+	h.Cache.DeleteBannersByID(ctx, []int{id})
+
 	response := map[string]string{"message": fmt.Sprintf("banner with ID (id %d) deleted", id)}
 	utils.RespondJSON(w, http.StatusOK, response)
 }
 
-func validateBanner(banner Banner) error {
-	validate := validator.New()
-	err := validate.Struct(banner)
+func (h *Handler) GetBannerVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		return err
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
 	}
-	return nil
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	revisions, err := h.repository.GetBannerRevisions(ctx, id)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, revisions)
+}
+
+func (h *Handler) RestoreBannerVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "Invalid version parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	revision, err := h.repository.RestoreBannerRevision(ctx, id, version)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, revision)
 }
 
-func getContextTimeout() (context.Context, context.CancelFunc) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+func getContextTimeout(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, time.Second*10)
 	return ctx, cancel
 }
 
@@ -1,6 +1,8 @@
 package banner
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -10,18 +12,193 @@ type Tag struct {
 }
 
 type Feature struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID     int             `json:"id"`
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema,omitempty"`
 }
 
 type Banner struct {
-	ID        int       `json:"id"`
+	ID               int             `json:"id"`
+	Title            string          `json:"title" validate:"required"`
+	Text             string          `json:"text" validate:"required_without=Content"`
+	URL              string          `json:"url" validate:"required_without=Content"`
+	Content          json.RawMessage `json:"content,omitempty"`
+	IsActive         bool            `json:"is_active"`
+	FeatureID        Feature         `json:"feature_id" validate:"required"`
+	Tags             []Tag           `json:"tags" validate:"required"`
+	StartsAt         *time.Time      `json:"starts_at,omitempty"`
+	EndsAt           *time.Time      `json:"ends_at,omitempty"`
+	Timezone         string          `json:"timezone,omitempty"`
+	Targeting        *Targeting      `json:"targeting,omitempty"`
+	FrequencyCap     *FrequencyCap   `json:"frequency_cap,omitempty"`
+	Version          int             `json:"version"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	DeletedAt        *time.Time      `json:"deleted_at,omitempty"`
+	Status           string          `json:"status,omitempty"`
+	PublishedVersion *int            `json:"published_version,omitempty"`
+	TemplateID       *int            `json:"template_id,omitempty"`
+	TemplateVersion  *int            `json:"template_version,omitempty"`
+	Rank             *float64        `json:"rank,omitempty"`
+	Highlight        *Highlight      `json:"highlight,omitempty"`
+	VariantID        *int            `json:"variant_id,omitempty"`
+	Locale           string          `json:"locale,omitempty"`
+	// Variants - варианты A/B-теста, из которых выбирается показываемый пользователю
+	Variants []Variant `json:"-"`
+	// Translations - переводы заголовка и текста, из которых выбирается язык пользователя
+	Translations []Translation `json:"-"`
+	// Variables - значения плейсхолдеров шаблона, из которого создан баннер
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// Template - шаблон баннера. В title, text, url и строковых значениях content
+// можно использовать плейсхолдеры {{name}}, объявленные в Variables.
+// Каждое изменение шаблона сохраняется как новая версия.
+type Template struct {
+	ID        int                `json:"id"`
+	Name      string             `json:"name"`
+	Title     string             `json:"title" validate:"required"`
+	Text      string             `json:"text"`
+	URL       string             `json:"url"`
+	Content   json.RawMessage    `json:"content,omitempty"`
+	Variables []TemplateVariable `json:"variables" validate:"dive"`
+	Version   int                `json:"version"`
+	AuthorID  *int               `json:"author_id,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// TemplateVariable - плейсхолдер шаблона; переменная без значения по умолчанию обязательна
+type TemplateVariable struct {
+	Name        string  `json:"name" validate:"required"`
+	Description string  `json:"description,omitempty"`
+	Default     *string `json:"default,omitempty"`
+}
+
+// Translation - перевод заголовка и текста баннера на язык Locale
+type Translation struct {
+	BannerID  int       `json:"banner_id"`
+	Locale    string    `json:"locale"`
 	Title     string    `json:"title" validate:"required"`
-	Text      string    `json:"text" validate:"required"`
-	URL       string    `json:"url" validate:"required"`
-	IsActive  bool      `json:"is_active"`
-	FeatureID Feature   `json:"feature_id" validate:"required"`
-	Tags      []Tag     `json:"tags" validate:"required"`
+	Text      string    `json:"text"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// Variant - вариант баннера для A/B-теста. Пустые поля берутся из самого баннера.
+type Variant struct {
+	ID        int             `json:"id"`
+	BannerID  int             `json:"banner_id"`
+	Name      string          `json:"name" validate:"required"`
+	Title     string          `json:"title"`
+	Text      string          `json:"text"`
+	URL       string          `json:"url"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	Weight    int             `json:"weight" validate:"gt=0"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Placement - место показа баннера, заданное парой (фича, тег)
+type Placement struct {
+	FeatureID int `json:"feature_id"`
+	TagID     int `json:"tag_id"`
+}
+
+// Highlight - фрагменты заголовка и текста с подсвеченными совпадениями поиска
+type Highlight struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// NormalizeSchedule проверяет часовой пояс баннера и переводит границы окна показа в него
+func (b *Banner) NormalizeSchedule() error {
+	if b.Timezone == "" {
+		b.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q", b.Timezone)
+	}
+	if b.StartsAt != nil {
+		startsAt := b.StartsAt.In(loc)
+		b.StartsAt = &startsAt
+	}
+	if b.EndsAt != nil {
+		endsAt := b.EndsAt.In(loc)
+		b.EndsAt = &endsAt
+	}
+	return nil
+}
+
+// IsActiveAt сообщает, включен ли баннер и попадает ли момент now в окно показа
+func (b *Banner) IsActiveAt(now time.Time) bool {
+	if !b.IsActive {
+		return false
+	}
+	if b.StartsAt != nil && now.Before(*b.StartsAt) {
+		return false
+	}
+	if b.EndsAt != nil && !now.Before(*b.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// nextScheduleChange возвращает ближайшую после now границу окна показа
+func (b *Banner) nextScheduleChange(now time.Time) (time.Time, bool) {
+	if b.StartsAt != nil && now.Before(*b.StartsAt) {
+		return *b.StartsAt, true
+	}
+	if b.EndsAt != nil && now.Before(*b.EndsAt) {
+		return *b.EndsAt, true
+	}
+	return time.Time{}, false
+}
+
+// Filter - параметры выборки списка баннеров, nil означает отсутствие фильтра.
+// Если задан After, выборка продолжается после курсора и Offset не используется.
+type Filter struct {
+	FeatureID *int
+	TagID     *int
+	Limit     int
+	Offset    int
+	Sort      string
+	Desc      bool
+	After     *Cursor
+	Query     string
+	Highlight bool
+	// Deleted выбирает баннеры из корзины вместо действующих
+	Deleted bool
+	Status  *string
+}
+
+// Page - страница списка баннеров
+type Page struct {
+	Banners    []*Banner
+	Total      int
+	NextCursor *Cursor
+}
+
+// Revision - неизменяемый снимок баннера, создаваемый при каждом изменении.
+// Ревизия хранит все поля баннера, которые видит пользователь, включая расписание и аудиторию.
+type Revision struct {
+	BannerID     int             `json:"banner_id"`
+	Version      int             `json:"version"`
+	Title        string          `json:"title"`
+	Text         string          `json:"text"`
+	URL          string          `json:"url"`
+	Content      json.RawMessage `json:"content,omitempty"`
+	IsActive     bool            `json:"is_active"`
+	FeatureID    int             `json:"feature_id"`
+	TagIDs       []int           `json:"tag_ids"`
+	StartsAt     *time.Time      `json:"starts_at,omitempty"`
+	EndsAt       *time.Time      `json:"ends_at,omitempty"`
+	Timezone     string          `json:"timezone"`
+	Targeting    *Targeting      `json:"targeting,omitempty"`
+	FrequencyCap *FrequencyCap   `json:"frequency_cap,omitempty"`
+	AuthorID     *int            `json:"author_id"`
+	RestoredFrom *int            `json:"restored_from,omitempty"`
+	IsPublished  bool            `json:"is_published"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
@@ -0,0 +1,129 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const maxPatchSize = 1 << 20
+
+// PatchBannerHandler частично обновляет баннер по JSON Merge Patch (RFC 7386)
+func (h *Handler) PatchBannerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+		http.Error(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	versions, err := parseIfMatch(r)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	patch, err := io.ReadAll(io.LimitReader(r.Body, maxPatchSize))
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var patchObject map[string]json.RawMessage
+	if err = json.Unmarshal(patch, &patchObject); err != nil {
+		http.Error(w, "patch must be a JSON object", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	banner, err := h.repository.PatchBanner(ctx, id, func(current *Banner) error {
+		original, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+		patched, err := mergePatch(original, patch)
+		if err != nil {
+			return err
+		}
+
+		var updated Banner
+		if err = json.Unmarshal(patched, &updated); err != nil {
+			return &ValidationError{Fields: []FieldError{{Field: "body", Message: err.Error()}}}
+		}
+		// Служебные поля изменять нельзя
+		updated.ID = current.ID
+		// Баннер уже заблокирован транзакцией, поэтому версию из списка If-Match можно сверить здесь
+		if len(versions) > 0 && !containsVersion(versions, current.Version) {
+			return ErrPreconditionFailed
+		}
+		updated.Version = current.Version
+		updated.CreatedAt = current.CreatedAt
+		updated.UpdatedAt = current.UpdatedAt
+		updated.Rank, updated.Highlight = nil, nil
+		if updated.FeatureID.ID != current.FeatureID.ID {
+			updated.FeatureID = Feature{ID: updated.FeatureID.ID}
+		}
+
+		if err = normalizeBanner(&updated); err != nil {
+			return err
+		}
+		if err = h.validateBanner(ctx, updated); err != nil {
+			return err
+		}
+
+		*current = updated
+		return nil
+	})
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	w.Header().Set("ETag", ETag(banner.Version))
+	utils.RespondJSON(w, http.StatusOK, banner)
+}
+
+// mergePatch применяет JSON Merge Patch к документу
+func mergePatch(document, patch []byte) ([]byte, error) {
+	var doc, p interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(patch))
+	decoder.UseNumber()
+	if err := decoder.Decode(&p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(doc, p))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
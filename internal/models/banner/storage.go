@@ -2,12 +2,127 @@ package banner
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 )
 
+var (
+	ErrBannerNotFound      = errors.New("banner not found")
+	ErrRevisionNotFound    = errors.New("banner revision not found")
+	ErrVariantNotFound     = errors.New("banner variant not found")
+	ErrTranslationNotFound = errors.New("banner translation not found")
+	ErrFeatureNotFound     = errors.New("feature not found")
+	ErrTagNotFound         = errors.New("tag not found")
+	ErrTemplateNotFound    = errors.New("template not found")
+	ErrAlreadyExists       = errors.New("name already exists")
+	// ErrPreconditionFailed возвращается, когда версия баннера не совпала с ожидаемой
+	ErrPreconditionFailed = errors.New("banner version has changed")
+	// ErrSelfReview возвращается, когда автор версии пытается сам ее одобрить или отклонить
+	ErrSelfReview = errors.New("author cannot review own banner version")
+)
+
+// ConflictError возвращается, когда пара (фича, тег) уже занята другими баннерами
+type ConflictError struct {
+	BannerIDs []int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("feature and tag pairs are already used by banners %v", e.BannerIDs)
+}
+
+// InUseError возвращается при удалении тега или фичи, на которые ссылаются баннеры
+type InUseError struct {
+	BannerIDs []int
+}
+
+func (e *InUseError) Error() string {
+	return fmt.Sprintf("still used by banners %v", e.BannerIDs)
+}
+
 type Storage interface {
-	GetBannerFromDB(ctx context.Context, tagID, featureID int, useLastRevision bool) (*Banner, error)
-	GetBannersByFiltering(ctx context.Context, featureID, tagID, limit, offset int) ([]*Banner, error)
+	// GetBannerCandidates возвращает все баннеры пары (фича, тег): с таргетингом и запасной без него
+	GetBannerCandidates(ctx context.Context, tagID, featureID int, useLastRevision bool) ([]*Banner, error)
+	// GetBannerCandidatesBatch возвращает кандидатов для нескольких пар одним запросом; пар без баннеров нет в результате
+	GetBannerCandidatesBatch(ctx context.Context, placements []Placement, useLastRevision bool) (map[Placement][]*Banner, error)
+	// GetPublishedBanner возвращает опубликованную ревизию баннера по идентификатору
+	GetPublishedBanner(ctx context.Context, id int) (*Banner, error)
+	// GetBannersByFiltering возвращает страницу баннеров и общее число баннеров, подходящих под фильтр
+	GetBannersByFiltering(ctx context.Context, filter Filter) (*Page, error)
 	CreateBanner(ctx context.Context, banner *Banner) error
+	// UpdateBanner сохраняет баннер. Если banner.Version не 0, баннер обновляется только
+	// при совпадении версии, иначе возвращается ErrPreconditionFailed.
 	UpdateBanner(ctx context.Context, banner *Banner) error
-	DeleteBanner(ctx context.Context, id int) error
+	// PatchBanner загружает баннер, применяет к нему apply и сохраняет результат в одной транзакции.
+	// Проверка версии выполняется так же, как в UpdateBanner, по Version, оставленной apply.
+	PatchBanner(ctx context.Context, id int, apply func(current *Banner) error) (*Banner, error)
+	// GetBannerVersion возвращает номер последней версии баннера вне корзины
+	GetBannerVersion(ctx context.Context, id int) (int, error)
+	// DeleteBanner переносит баннер в корзину; version 0 отключает проверку версии
+	DeleteBanner(ctx context.Context, id, version int) error
+	// ChangeStatus блокирует баннер, передает apply его состояние согласования и сохраняет
+	// новый статус. version 0 отключает проверку версии, иначе возвращается ErrPreconditionFailed.
+	ChangeStatus(ctx context.Context, id, version int, apply func(state *WorkflowState) error) (*WorkflowState, error)
+	// RestoreBanner возвращает баннер из корзины
+	RestoreBanner(ctx context.Context, id int) error
+	// PurgeDeletedBanners окончательно удаляет до limit баннеров, удаленных раньше before, и возвращает их идентификаторы
+	PurgeDeletedBanners(ctx context.Context, before time.Time, limit int) ([]int, error)
+	GetBannerRevisions(ctx context.Context, bannerID int) ([]*Revision, error)
+	RestoreBannerRevision(ctx context.Context, bannerID, version int) (*Revision, error)
+	GetFeatureSchema(ctx context.Context, featureID int) (json.RawMessage, error)
+	// GetBannerURL возвращает URL опубликованной ревизии баннера или URL варианта, если он задан
+	GetBannerURL(ctx context.Context, id int, variantID *int) (string, error)
+	// GetBannerSchema возвращает JSON Schema фичи баннера
+	GetBannerSchema(ctx context.Context, bannerID int) (json.RawMessage, error)
+	GetVariants(ctx context.Context, bannerID int) ([]Variant, error)
+	CreateVariant(ctx context.Context, variant *Variant) error
+	UpdateVariant(ctx context.Context, variant *Variant) error
+	DeleteVariant(ctx context.Context, bannerID, variantID int) error
+	GetTranslations(ctx context.Context, bannerID int) ([]Translation, error)
+	// SaveTranslation создает или заменяет перевод баннера на язык translation.Locale
+	SaveTranslation(ctx context.Context, translation *Translation) error
+	DeleteTranslation(ctx context.Context, bannerID int, locale string) error
+	// ExportBanners по очереди передает в fn все баннеры вне корзины вместе с тегами и фичей
+	ExportBanners(ctx context.Context, fn func(*Banner) error) error
+	// GetFeatureAndTagIDs возвращает идентификаторы фич и тегов по их именам
+	GetFeatureAndTagIDs(ctx context.Context) (features, tags map[string]int, err error)
+	// ImportBanners создает или обновляет по заголовку баннеры строк без ошибок в одной транзакции.
+	// Конфликты записываются в строки; транзакция фиксируется, только если commit и ошибок нет.
+	ImportBanners(ctx context.Context, items []*ImportItem, commit bool) error
+	// DeleteBannersBatch переносит в корзину до limit баннеров с заданной фичей и/или тегом и возвращает их идентификаторы
+	DeleteBannersBatch(ctx context.Context, featureID, tagID *int, limit int) ([]int, error)
+}
+
+type TagStorage interface {
+	GetTags(ctx context.Context) ([]Tag, error)
+	GetTag(ctx context.Context, id int) (*Tag, error)
+	CreateTag(ctx context.Context, tag *Tag) error
+	UpdateTag(ctx context.Context, tag *Tag) error
+	// DeleteTag удаляет тег; с cascade окончательно удаляются и все баннеры с этим тегом,
+	// в том числе не перенесенные в корзину, их идентификаторы возвращаются.
+	DeleteTag(ctx context.Context, id int, cascade bool) ([]int, error)
+}
+
+type FeatureStorage interface {
+	GetFeatures(ctx context.Context) ([]Feature, error)
+	GetFeature(ctx context.Context, id int) (*Feature, error)
+	CreateFeature(ctx context.Context, feature *Feature) error
+	UpdateFeature(ctx context.Context, feature *Feature) error
+	// DeleteFeature удаляет фичу; с cascade окончательно удаляются и все ее баннеры,
+	// в том числе не перенесенные в корзину, их идентификаторы возвращаются.
+	DeleteFeature(ctx context.Context, id int, cascade bool) ([]int, error)
+}
+
+type TemplateStorage interface {
+	GetTemplates(ctx context.Context) ([]Template, error)
+	// GetTemplate возвращает версию version шаблона; version 0 - последнюю
+	GetTemplate(ctx context.Context, id, version int) (*Template, error)
+	// GetTemplateVersions возвращает все версии шаблона, начиная с последней
+	GetTemplateVersions(ctx context.Context, id int) ([]Template, error)
+	CreateTemplate(ctx context.Context, template *Template) error
+	// UpdateTemplate сохраняет новую версию шаблона; баннеры остаются привязаны к версиям, из которых созданы
+	UpdateTemplate(ctx context.Context, template *Template) error
+	// DeleteTemplate удаляет шаблон со всеми версиями. Если из него созданы баннеры, возвращается InUseError.
+	DeleteTemplate(ctx context.Context, id int) error
 }
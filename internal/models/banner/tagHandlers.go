@@ -0,0 +1,148 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"banner-service/pkg/logging"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type TagHandler struct {
+	logger     *logging.Logger
+	repository TagStorage
+	// Cache - кэш баннеров, из которого убираются баннеры, удаленные вместе с тегом
+	Cache *CacheBanner
+}
+
+func NewTagHandler(repository TagStorage, logger *logging.Logger) *TagHandler {
+	return &TagHandler{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	tags, err := h.repository.GetTags(ctx)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, tags)
+}
+
+func (h *TagHandler) GetTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	tag, err := h.repository.GetTag(ctx, id)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, tag)
+}
+
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var tag Tag
+	err := json.NewDecoder(r.Body).Decode(&tag)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = validateName(tag.Name)
+	if err == nil {
+		err = h.repository.CreateTag(ctx, &tag)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, tag)
+}
+
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	var tag Tag
+	err = json.NewDecoder(r.Body).Decode(&tag)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tag.ID = id
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = validateName(tag.Name)
+	if err == nil {
+		err = h.repository.UpdateTag(ctx, &tag)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, tag)
+}
+
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID parameter", http.StatusBadRequest)
+		return
+	}
+	// cascade=true окончательно удаляет и все баннеры, использующие тег
+	cascade, err := strconv.ParseBool(r.URL.Query().Get("cascade"))
+	if err != nil {
+		cascade = false
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	deleted, err := h.repository.DeleteTag(ctx, id, cascade)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	if h.Cache != nil && len(deleted) > 0 {
+		h.Cache.DeleteBannersByID(ctx, deleted)
+	}
+
+	response := map[string]string{"message": fmt.Sprintf("tag with ID (id %d) deleted", id)}
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return &ValidationError{Fields: []FieldError{{Field: "name", Message: "failed on the 'required' rule"}}}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package banner
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Платформы, для которых можно настроить таргетинг
+var platforms = map[string]bool{
+	"ios":     true,
+	"android": true,
+	"web":     true,
+}
+
+var (
+	countryPattern   = regexp.MustCompile(`^[A-Z]{2}$`)
+	attributePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+)
+
+// Targeting - правила выбора аудитории баннера. Баннер подходит пользователю, если
+// выполнены все заданные условия; внутри списка достаточно совпадения с одним значением.
+type Targeting struct {
+	Countries  []string            `json:"countries,omitempty"`
+	Platforms  []string            `json:"platforms,omitempty"`
+	AppVersion *VersionRange       `json:"app_version,omitempty"`
+	Attributes map[string][]string `json:"attributes,omitempty"`
+	// Priority определяет порядок проверки баннеров с таргетингом, больший - раньше
+	Priority int `json:"priority,omitempty"`
+}
+
+// VersionRange - диапазон версий приложения, границы включаются
+type VersionRange struct {
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+}
+
+// Audience - атрибуты пользователя, по которым проверяется таргетинг
+type Audience struct {
+	Country    string
+	Platform   string
+	AppVersion string
+	Attributes map[string]string
+}
+
+// AudienceFromRequest собирает атрибуты пользователя из параметров запроса country,
+// platform, app_version и attr.<key> или из заголовков X-Country, X-Platform,
+// X-App-Version и X-Attr-<Key>. Параметры запроса имеют приоритет над заголовками.
+func AudienceFromRequest(r *http.Request) Audience {
+	query := r.URL.Query()
+	value := func(param, header string) string {
+		if v := query.Get(param); v != "" {
+			return v
+		}
+		return r.Header.Get(header)
+	}
+
+	audience := Audience{
+		Country:    strings.ToUpper(value("country", "X-Country")),
+		Platform:   strings.ToLower(value("platform", "X-Platform")),
+		AppVersion: value("app_version", "X-App-Version"),
+		Attributes: make(map[string]string),
+	}
+	for name, values := range r.Header {
+		if key, ok := strings.CutPrefix(name, "X-Attr-"); ok && len(values) > 0 {
+			audience.Attributes[strings.ToLower(key)] = values[0]
+		}
+	}
+	for name, values := range query {
+		if key, ok := strings.CutPrefix(name, "attr."); ok && len(values) > 0 {
+			audience.Attributes[strings.ToLower(key)] = values[0]
+		}
+	}
+	return audience
+}
+
+// Matches проверяет, подходит ли пользователь под правила
+func (t *Targeting) Matches(audience Audience) bool {
+	if t == nil {
+		return true
+	}
+	if len(t.Countries) > 0 && !contains(t.Countries, audience.Country) {
+		return false
+	}
+	if len(t.Platforms) > 0 && !contains(t.Platforms, audience.Platform) {
+		return false
+	}
+	if t.AppVersion != nil {
+		version, ok := parseVersion(audience.AppVersion)
+		if !ok {
+			return false
+		}
+		if t.AppVersion.Min != "" {
+			min, _ := parseVersion(t.AppVersion.Min)
+			if compareVersions(version, min) < 0 {
+				return false
+			}
+		}
+		if t.AppVersion.Max != "" {
+			max, _ := parseVersion(t.AppVersion.Max)
+			if compareVersions(version, max) > 0 {
+				return false
+			}
+		}
+	}
+	for key, allowed := range t.Attributes {
+		value, ok := audience.Attributes[key]
+		if !ok || !contains(allowed, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize приводит значения правил к каноническому виду; пустые правила означают отсутствие таргетинга
+func (t *Targeting) normalize() *Targeting {
+	if t == nil {
+		return nil
+	}
+	for i, country := range t.Countries {
+		t.Countries[i] = strings.ToUpper(strings.TrimSpace(country))
+	}
+	for i, platform := range t.Platforms {
+		t.Platforms[i] = strings.ToLower(strings.TrimSpace(platform))
+	}
+	if t.AppVersion != nil && t.AppVersion.Min == "" && t.AppVersion.Max == "" {
+		t.AppVersion = nil
+	}
+	if len(t.Countries) == 0 && len(t.Platforms) == 0 && t.AppVersion == nil && len(t.Attributes) == 0 {
+		return nil
+	}
+	return t
+}
+
+// validate проверяет правила таргетинга и возвращает ошибки по полям
+func (t *Targeting) validate() []FieldError {
+	if t == nil {
+		return nil
+	}
+
+	var fields []FieldError
+	for i, country := range t.Countries {
+		if !countryPattern.MatchString(country) {
+			fields = append(fields, FieldError{Field: fmt.Sprintf("targeting.countries[%d]", i), Message: "must be an ISO 3166-1 alpha-2 code"})
+		}
+	}
+	for i, platform := range t.Platforms {
+		if !platforms[platform] {
+			fields = append(fields, FieldError{Field: fmt.Sprintf("targeting.platforms[%d]", i), Message: "must be one of ios, android, web"})
+		}
+	}
+	if t.AppVersion != nil {
+		min, minOK := parseVersion(t.AppVersion.Min)
+		max, maxOK := parseVersion(t.AppVersion.Max)
+		if t.AppVersion.Min != "" && !minOK {
+			fields = append(fields, FieldError{Field: "targeting.app_version.min", Message: "must be a version like 1.2.3"})
+		}
+		if t.AppVersion.Max != "" && !maxOK {
+			fields = append(fields, FieldError{Field: "targeting.app_version.max", Message: "must be a version like 1.2.3"})
+		}
+		if minOK && maxOK && compareVersions(min, max) > 0 {
+			fields = append(fields, FieldError{Field: "targeting.app_version", Message: "min must not be greater than max"})
+		}
+	}
+	keys := make([]string, 0, len(t.Attributes))
+	for key := range t.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !attributePattern.MatchString(key) {
+			fields = append(fields, FieldError{Field: "targeting.attributes." + key, Message: "key must contain only lowercase letters, digits, '_' and '-'"})
+		} else if len(t.Attributes[key]) == 0 {
+			fields = append(fields, FieldError{Field: "targeting.attributes." + key, Message: "must contain at least one value"})
+		}
+	}
+	return fields
+}
+
+// SelectBanner выбирает первый подходящий пользователю баннер. Кандидаты с таргетингом
+// проверяются по убыванию приоритета, баннер без таргетинга используется как запасной.
+func SelectBanner(candidates []*Banner, audience Audience, available func(*Banner) bool) *Banner {
+	ordered := make([]*Banner, len(candidates))
+	copy(ordered, candidates)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i].Targeting, ordered[j].Targeting
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return ordered[i].UpdatedAt.After(ordered[j].UpdatedAt)
+	})
+
+	for _, banner := range ordered {
+		if available(banner) && banner.Targeting.Matches(audience) {
+			return banner
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVersion разбирает версию вида 1.2.3 в список чисел
+func parseVersion(value string) ([]int, bool) {
+	if value == "" {
+		return nil, false
+	}
+	parts := strings.Split(value, ".")
+	version := make([]int, 0, len(parts))
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		version = append(version, n)
+	}
+	return version, true
+}
+
+// compareVersions сравнивает версии покомпонентно, недостающие компоненты считаются нулями
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,218 @@
+package banner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"regexp"
+	"sort"
+)
+
+var (
+	placeholderPattern  = regexp.MustCompile(`\{\{\s*([^{}\s]*)\s*\}\}`)
+	variableNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// render подставляет значения переменных в копию шаблона. Для переменных без значения
+// берется значение по умолчанию; переменные, которых нет в шаблоне, считаются ошибкой.
+func (t *Template) render(values map[string]string) (*Template, error) {
+	var fields []FieldError
+	resolved := make(map[string]string, len(t.Variables))
+	for _, variable := range t.Variables {
+		value, ok := values[variable.Name]
+		if !ok && variable.Default != nil {
+			value, ok = *variable.Default, true
+		}
+		if !ok {
+			fields = append(fields, FieldError{Field: "variables." + variable.Name, Message: "is required by template"})
+			continue
+		}
+		resolved[variable.Name] = value
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !t.hasVariable(name) {
+			fields = append(fields, FieldError{Field: "variables." + name, Message: "is not declared by template"})
+		}
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	replace := func(s string) string {
+		return placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+			return resolved[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+		})
+	}
+
+	rendered := *t
+	rendered.Title = replace(t.Title)
+	rendered.Text = replace(t.Text)
+	rendered.URL = replace(t.URL)
+	if t.Content != nil {
+		// Подстановка выполняется в разобранных строках, чтобы значения экранировались как JSON
+		content, err := mapContentStrings(t.Content, func(s string) (string, error) { return replace(s), nil })
+		if err != nil {
+			return nil, err
+		}
+		rendered.Content = content
+	}
+	return &rendered, nil
+}
+
+func (t *Template) hasVariable(name string) bool {
+	for _, variable := range t.Variables {
+		if variable.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mapContentStrings применяет fn ко всем строковым значениям JSON-документа
+func mapContentStrings(content json.RawMessage, fn func(string) (string, error)) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var doc interface{}
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var walk func(value interface{}) (interface{}, error)
+	walk = func(value interface{}) (interface{}, error) {
+		var err error
+		switch v := value.(type) {
+		case string:
+			return fn(v)
+		case []interface{}:
+			for i := range v {
+				if v[i], err = walk(v[i]); err != nil {
+					return nil, err
+				}
+			}
+		case map[string]interface{}:
+			for key := range v {
+				if v[key], err = walk(v[key]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return value, nil
+	}
+	doc, err = walk(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// validateTemplate проверяет поля шаблона и то, что все его плейсхолдеры объявлены
+func validateTemplate(template *Template) error {
+	err := validateName(template.Name)
+	if err != nil {
+		return err
+	}
+	if string(template.Content) == "null" {
+		template.Content = nil
+	}
+	if template.Variables == nil {
+		template.Variables = make([]TemplateVariable, 0)
+	}
+
+	var fields []FieldError
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	if err := validate.Struct(template); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+		for _, fe := range validationErrors {
+			fields = append(fields, FieldError{Field: fieldPath(fe.Namespace()), Message: fmt.Sprintf("failed on the '%s' rule", fe.Tag())})
+		}
+	}
+
+	declared := make(map[string]bool, len(template.Variables))
+	for i, variable := range template.Variables {
+		field := fmt.Sprintf("variables[%d].name", i)
+		if variable.Name != "" && !variableNamePattern.MatchString(variable.Name) {
+			fields = append(fields, FieldError{Field: field, Message: "must contain only letters, digits and underscores"})
+		}
+		if declared[variable.Name] {
+			fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf("duplicate variable %q", variable.Name)})
+		}
+		declared[variable.Name] = true
+	}
+
+	undeclared := func(field, s string) {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+			if !declared[match[1]] {
+				fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf("placeholder %q is not declared in variables", match[0])})
+			}
+		}
+	}
+	undeclared("title", template.Title)
+	undeclared("text", template.Text)
+	undeclared("url", template.URL)
+	if template.Content != nil {
+		_, err := mapContentStrings(template.Content, func(s string) (string, error) {
+			undeclared("content", s)
+			return s, nil
+		})
+		if err != nil {
+			fields = append(fields, FieldError{Field: "content", Message: "invalid JSON"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// applyTemplate заполняет пустые заголовок, текст, URL и content баннера из шаблона
+// banner.TemplateID. Без banner.TemplateVersion используется последняя версия шаблона.
+func (h *Handler) applyTemplate(ctx context.Context, banner *Banner) error {
+	if h.Templates == nil {
+		return errors.New("banner templates are not configured")
+	}
+
+	version := 0
+	if banner.TemplateVersion != nil {
+		version = *banner.TemplateVersion
+	}
+	template, err := h.Templates.GetTemplate(ctx, *banner.TemplateID, version)
+	if err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			return &ValidationError{Fields: []FieldError{{Field: "template_id", Message: "template version does not exist"}}}
+		}
+		return err
+	}
+
+	rendered, err := template.render(banner.Variables)
+	if err != nil {
+		return err
+	}
+	if banner.Title == "" {
+		banner.Title = rendered.Title
+	}
+	if banner.Text == "" {
+		banner.Text = rendered.Text
+	}
+	if banner.URL == "" {
+		banner.URL = rendered.URL
+	}
+	if banner.Content == nil {
+		banner.Content = rendered.Content
+	}
+	banner.TemplateVersion = &template.Version
+	return nil
+}
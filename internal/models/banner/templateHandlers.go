@@ -0,0 +1,159 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"banner-service/pkg/logging"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+type TemplateHandler struct {
+	logger     *logging.Logger
+	repository TemplateStorage
+}
+
+func NewTemplateHandler(repository TemplateStorage, logger *logging.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (h *TemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	templates, err := h.repository.GetTemplates(ctx)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, templates)
+}
+
+// GetTemplate отдает последнюю версию шаблона или версию из параметра version
+func (h *TemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID parameter", http.StatusBadRequest)
+		return
+	}
+	version := 0
+	if value := r.URL.Query().Get("version"); value != "" {
+		version, err = strconv.Atoi(value)
+		if err != nil || version <= 0 {
+			http.Error(w, "Invalid version parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	template, err := h.repository.GetTemplate(ctx, id, version)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, template)
+}
+
+func (h *TemplateHandler) GetTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	versions, err := h.repository.GetTemplateVersions(ctx, id)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, versions)
+}
+
+func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var template Template
+	err := json.NewDecoder(r.Body).Decode(&template)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = validateTemplate(&template)
+	if err == nil {
+		err = h.repository.CreateTemplate(ctx, &template)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, template)
+}
+
+// UpdateTemplate сохраняет тело запроса как новую версию шаблона
+func (h *TemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	var template Template
+	err = json.NewDecoder(r.Body).Decode(&template)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	template.ID = id
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = validateTemplate(&template)
+	if err == nil {
+		err = h.repository.UpdateTemplate(ctx, &template)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, template)
+}
+
+func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = h.repository.DeleteTemplate(ctx, id)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	response := map[string]string{"message": fmt.Sprintf("template with ID (id %d) deleted", id)}
+	utils.RespondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,470 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxImportSize  = 10 << 20
+	maxImportRows  = 5000
+	importTimeout  = time.Minute
+	csvTagSplitter = "|"
+)
+
+const (
+	ImportCreate = "create"
+	ImportUpdate = "update"
+)
+
+// csvColumns - колонки CSV при экспорте и импорте. Теги перечисляются через "|",
+// content, targeting и frequency_cap записываются в виде JSON.
+var csvColumns = []string{"title", "text", "url", "content", "is_active", "feature", "tags", "starts_at", "ends_at", "timezone", "targeting", "frequency_cap"}
+
+// TransferBanner - переносимое между окружениями представление баннера:
+// фича и теги указываются по именам, а не по идентификаторам
+type TransferBanner struct {
+	Title        string          `json:"title"`
+	Text         string          `json:"text"`
+	URL          string          `json:"url"`
+	Content      json.RawMessage `json:"content,omitempty"`
+	IsActive     bool            `json:"is_active"`
+	Feature      string          `json:"feature"`
+	Tags         []string        `json:"tags"`
+	StartsAt     *time.Time      `json:"starts_at,omitempty"`
+	EndsAt       *time.Time      `json:"ends_at,omitempty"`
+	Timezone     string          `json:"timezone,omitempty"`
+	Targeting    *Targeting      `json:"targeting,omitempty"`
+	FrequencyCap *FrequencyCap   `json:"frequency_cap,omitempty"`
+}
+
+// ImportItem - строка импорта и результат ее обработки
+type ImportItem struct {
+	Row         int          `json:"row"`
+	Title       string       `json:"title"`
+	Action      string       `json:"action,omitempty"`
+	BannerID    int          `json:"banner_id,omitempty"`
+	ConflictIDs []int        `json:"conflict_banner_ids,omitempty"`
+	Errors      []FieldError `json:"errors,omitempty"`
+	Banner      *Banner      `json:"-"`
+}
+
+// ImportReport - отчет об импорте. Committed false означает, что ничего не записано.
+type ImportReport struct {
+	DryRun    bool          `json:"dry_run"`
+	Committed bool          `json:"committed"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Failed    int           `json:"failed"`
+	Rows      []*ImportItem `json:"rows"`
+}
+
+func newTransferBanner(b *Banner) TransferBanner {
+	tags := make([]string, 0, len(b.Tags))
+	for _, tag := range b.Tags {
+		tags = append(tags, tag.Name)
+	}
+	return TransferBanner{
+		Title:        b.Title,
+		Text:         b.Text,
+		URL:          b.URL,
+		Content:      b.Content,
+		IsActive:     b.IsActive,
+		Feature:      b.FeatureID.Name,
+		Tags:         tags,
+		StartsAt:     b.StartsAt,
+		EndsAt:       b.EndsAt,
+		Timezone:     b.Timezone,
+		Targeting:    b.Targeting,
+		FrequencyCap: b.FrequencyCap,
+	}
+}
+
+// ExportBanners выгружает все баннеры вне корзины в JSON или CSV, не собирая их в памяти
+func (h *Handler) ExportBanners(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	started := false
+	start := func() {
+		started = true
+		contentType := "application/json"
+		if format == "csv" {
+			contentType = "text/csv; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="banners.%s"`, format))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	var write func(*Banner) error
+	var finish func() error
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		write = func(b *Banner) error {
+			if !started {
+				start()
+				if err := writer.Write(csvColumns); err != nil {
+					return err
+				}
+			}
+			record, err := csvRecord(newTransferBanner(b))
+			if err != nil {
+				return err
+			}
+			return writer.Write(record)
+		}
+		finish = func() error {
+			if !started {
+				start()
+				_ = writer.Write(csvColumns)
+			}
+			writer.Flush()
+			return writer.Error()
+		}
+	} else {
+		write = func(b *Banner) error {
+			separator := ",\n"
+			if !started {
+				start()
+				separator = "[\n"
+			}
+			data, err := json.Marshal(newTransferBanner(b))
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, separator+string(data))
+			return err
+		}
+		finish = func() error {
+			closing := "\n]\n"
+			if !started {
+				start()
+				closing = "[]\n"
+			}
+			_, err := io.WriteString(w, closing)
+			return err
+		}
+	}
+
+	err := h.repository.ExportBanners(r.Context(), write)
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		// После начала выгрузки статус уже не изменить, клиент получит оборванный файл
+		if !started {
+			handleErrors(err, h.logger, w)
+			return
+		}
+		h.logger.Errorf("banner export failed: %v", err)
+	}
+}
+
+// ImportBanners создает или обновляет баннеры по заголовку в одной транзакции.
+// Если хотя бы одна строка содержит ошибку, ничего не записывается. С dry_run=true
+// импорт только проверяется, включая конфликты пар (фича, тег).
+func (h *Handler) ImportBanners(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "text/csv" {
+			format = "csv"
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	var records []*transferRecord
+	var err error
+	switch format {
+	case "json":
+		records, err = readJSONRecords(body)
+	case "csv":
+		records, err = readCSVRecords(body)
+	default:
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(records) == 0 || len(records) > maxImportRows {
+		http.Error(w, fmt.Sprintf("import must contain from 1 to %d banners", maxImportRows), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), importTimeout)
+	defer cancel()
+
+	items, err := h.prepareImport(ctx, records)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	valid := true
+	for _, item := range items {
+		valid = valid && len(item.Errors) == 0
+	}
+	err = h.repository.ImportBanners(ctx, items, valid && !dryRun)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	report := &ImportReport{DryRun: dryRun, Rows: items}
+	var updated []int
+	for _, item := range items {
+		switch {
+		case len(item.Errors) > 0:
+			report.Failed++
+		case item.Action == ImportCreate:
+			report.Created++
+		case item.Action == ImportUpdate:
+			report.Updated++
+			updated = append(updated, item.BannerID)
+		}
+	}
+	report.Committed = !dryRun && report.Failed == 0
+	if report.Committed {
+		h.Cache.DeleteBannersByID(ctx, updated)
+	}
+
+	status := http.StatusOK
+	if !dryRun && report.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	utils.RespondJSON(w, status, report)
+}
+
+// prepareImport переводит имена фич и тегов в идентификаторы и проверяет баннеры так же, как при создании
+func (h *Handler) prepareImport(ctx context.Context, records []*transferRecord) ([]*ImportItem, error) {
+	features, tags, err := h.repository.GetFeatureAndTagIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	titles := make(map[string]int, len(records))
+	items := make([]*ImportItem, 0, len(records))
+	for i, record := range records {
+		item := &ImportItem{Row: i + 1, Title: record.banner.Title, Errors: record.errors}
+		items = append(items, item)
+		if len(item.Errors) > 0 {
+			continue
+		}
+
+		banner := &Banner{
+			Title:        record.banner.Title,
+			Text:         record.banner.Text,
+			URL:          record.banner.URL,
+			Content:      record.banner.Content,
+			IsActive:     record.banner.IsActive,
+			StartsAt:     record.banner.StartsAt,
+			EndsAt:       record.banner.EndsAt,
+			Timezone:     record.banner.Timezone,
+			Targeting:    record.banner.Targeting,
+			FrequencyCap: record.banner.FrequencyCap,
+			Tags:         make([]Tag, 0, len(record.banner.Tags)),
+		}
+		if row, ok := titles[banner.Title]; ok {
+			item.Errors = append(item.Errors, FieldError{Field: "title", Message: fmt.Sprintf("duplicates row %d", row)})
+		}
+		titles[banner.Title] = item.Row
+
+		if id, ok := features[record.banner.Feature]; ok {
+			banner.FeatureID = Feature{ID: id, Name: record.banner.Feature}
+		} else {
+			item.Errors = append(item.Errors, FieldError{Field: "feature", Message: fmt.Sprintf("feature %q does not exist", record.banner.Feature)})
+		}
+		for _, name := range record.banner.Tags {
+			if id, ok := tags[name]; ok {
+				banner.Tags = append(banner.Tags, Tag{ID: id, Name: name})
+			} else {
+				item.Errors = append(item.Errors, FieldError{Field: "tags", Message: fmt.Sprintf("tag %q does not exist", name)})
+			}
+		}
+		if len(item.Errors) > 0 {
+			continue
+		}
+
+		err = normalizeBanner(banner)
+		if err == nil {
+			err = h.validateBanner(ctx, *banner)
+		}
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			item.Errors = append(item.Errors, validationErr.Fields...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		item.Banner = banner
+	}
+
+	return items, nil
+}
+
+// transferRecord - прочитанная строка импорта; errors содержит ошибки разбора
+type transferRecord struct {
+	banner TransferBanner
+	errors []FieldError
+}
+
+// readJSONRecords читает массив баннеров. Строки разбираются по отдельности,
+// чтобы ошибка в одной из них попала в отчет, а не прервала весь импорт.
+func readJSONRecords(body io.Reader) ([]*transferRecord, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("import must be a JSON array of banners: %w", err)
+	}
+
+	records := make([]*transferRecord, 0, len(raw))
+	for _, data := range raw {
+		record := &transferRecord{}
+		if err := json.Unmarshal(data, &record.banner); err != nil {
+			record.errors = []FieldError{{Field: "body", Message: err.Error()}}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func readCSVRecords(body io.Reader) ([]*transferRecord, error) {
+	reader := csv.NewReader(body)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("import must be a CSV file with a header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"title", "feature", "tags"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must contain column %q", required)
+		}
+	}
+
+	var records []*transferRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, parseCSVRecord(columns, row))
+	}
+	return records, nil
+}
+
+func parseCSVRecord(columns map[string]int, row []string) *transferRecord {
+	record := &transferRecord{}
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return row[i]
+		}
+		return ""
+	}
+	fail := func(field string, err error) {
+		record.errors = append(record.errors, FieldError{Field: field, Message: err.Error()})
+	}
+
+	b := &record.banner
+	b.Title, b.Text, b.URL = value("title"), value("text"), value("url")
+	b.Feature, b.Timezone = value("feature"), value("timezone")
+	b.Tags = make([]string, 0)
+	for _, name := range strings.Split(value("tags"), csvTagSplitter) {
+		if name = strings.TrimSpace(name); name != "" {
+			b.Tags = append(b.Tags, name)
+		}
+	}
+	if content := value("content"); content != "" {
+		b.Content = json.RawMessage(content)
+		if !json.Valid(b.Content) {
+			fail("content", errors.New("invalid JSON"))
+		}
+	}
+	if active := value("is_active"); active != "" {
+		var err error
+		if b.IsActive, err = strconv.ParseBool(active); err != nil {
+			fail("is_active", err)
+		}
+	}
+	parseTime := func(field string) *time.Time {
+		v := value(field)
+		if v == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			fail(field, err)
+			return nil
+		}
+		return &t
+	}
+	b.StartsAt, b.EndsAt = parseTime("starts_at"), parseTime("ends_at")
+	if v := value("targeting"); v != "" {
+		if err := json.Unmarshal([]byte(v), &b.Targeting); err != nil {
+			fail("targeting", err)
+		}
+	}
+	if v := value("frequency_cap"); v != "" {
+		if err := json.Unmarshal([]byte(v), &b.FrequencyCap); err != nil {
+			fail("frequency_cap", err)
+		}
+	}
+	return record
+}
+
+func csvRecord(b TransferBanner) ([]string, error) {
+	optionalJSON := func(value interface{}, isNil bool) (string, error) {
+		if isNil {
+			return "", nil
+		}
+		data, err := json.Marshal(value)
+		return string(data), err
+	}
+	optionalTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	targeting, err := optionalJSON(b.Targeting, b.Targeting == nil)
+	if err != nil {
+		return nil, err
+	}
+	frequencyCap, err := optionalJSON(b.FrequencyCap, b.FrequencyCap == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		b.Title, b.Text, b.URL, string(b.Content), strconv.FormatBool(b.IsActive), b.Feature,
+		strings.Join(b.Tags, csvTagSplitter), optionalTime(b.StartsAt), optionalTime(b.EndsAt), b.Timezone,
+		targeting, frequencyCap,
+	}, nil
+}
@@ -0,0 +1,232 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// localePattern - упрощенный языковой тег BCP 47: язык и необязательные подтеги (en, pt-br, zh-hant-tw)
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+func (h *Handler) GetTranslations(w http.ResponseWriter, r *http.Request) {
+	bannerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	translations, err := h.repository.GetTranslations(ctx, bannerID)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, translations)
+}
+
+// PutTranslation создает или заменяет перевод баннера на язык из пути запроса
+func (h *Handler) PutTranslation(w http.ResponseWriter, r *http.Request) {
+	bannerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	var translation Translation
+	err = json.NewDecoder(r.Body).Decode(&translation)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	translation.BannerID = bannerID
+	translation.Locale = strings.ToLower(chi.URLParam(r, "locale"))
+
+	err = validateTranslation(&translation)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = h.repository.SaveTranslation(ctx, &translation)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	h.Cache.DeleteBannersByID(ctx, []int{bannerID})
+
+	utils.RespondJSON(w, http.StatusOK, translation)
+}
+
+func (h *Handler) DeleteTranslation(w http.ResponseWriter, r *http.Request) {
+	bannerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+	locale := strings.ToLower(chi.URLParam(r, "locale"))
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = h.repository.DeleteTranslation(ctx, bannerID, locale)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	h.Cache.DeleteBannersByID(ctx, []int{bannerID})
+
+	response := map[string]string{"message": fmt.Sprintf("translation %q of banner with ID (id %d) deleted", locale, bannerID)}
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+func validateTranslation(translation *Translation) error {
+	var fields []FieldError
+	if !localePattern.MatchString(translation.Locale) {
+		fields = append(fields, FieldError{Field: "locale", Message: "must be a language tag like \"en\" or \"pt-br\""})
+	}
+
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	if err := validate.Struct(translation); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+		for _, fe := range validationErrors {
+			fields = append(fields, FieldError{Field: fieldPath(fe.Namespace()), Message: fmt.Sprintf("failed on the '%s' rule", fe.Tag())})
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// NegotiateLocale выбирает язык ответа из supported. Параметр lang важнее Accept-Language;
+// для регионального тега подходит и его базовый язык (en-US -> en). Если ни один
+// из запрошенных языков не поддерживается, берется первый из supported.
+func NegotiateLocale(r *http.Request, supported []string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+
+	requested := acceptLanguages(r.Header.Get("Accept-Language"))
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		requested = append([]string{lang}, requested...)
+	}
+	for _, tag := range requested {
+		base, _, _ := strings.Cut(tag, "-")
+		for _, candidate := range []string{tag, base} {
+			for _, locale := range supported {
+				if strings.EqualFold(locale, candidate) {
+					return locale
+				}
+			}
+		}
+	}
+	return supported[0]
+}
+
+// acceptLanguages возвращает языки из Accept-Language в порядке убывания q.
+// Языки с q=0 и "*" пропускаются: на их месте срабатывает цепочка по умолчанию.
+func acceptLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var languages []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		languages = append(languages, weighted{tag: tag, q: q})
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].q > languages[j].q
+	})
+
+	tags := make([]string, 0, len(languages))
+	for _, language := range languages {
+		tags = append(tags, language.tag)
+	}
+	return tags
+}
+
+// localeChain - порядок поиска перевода: выбранный язык, затем остальные поддерживаемые
+func localeChain(locale string, supported []string) []string {
+	if locale == "" {
+		return nil
+	}
+	chain := []string{locale}
+	for _, fallback := range supported {
+		if fallback != locale {
+			chain = append(chain, fallback)
+		}
+	}
+	return chain
+}
+
+// localize возвращает копию баннера с первым переводом по цепочке языков.
+// Если перевода нет ни на один из них, показываются заголовок и текст самого баннера.
+func (b *Banner) localize(chain []string) *Banner {
+	for _, locale := range chain {
+		for i := range b.Translations {
+			translation := &b.Translations[i]
+			if !strings.EqualFold(translation.Locale, locale) {
+				continue
+			}
+			shown := *b
+			shown.Locale = translation.Locale
+			shown.Title = translation.Title
+			if translation.Text != "" {
+				shown.Text = translation.Text
+			}
+			if translation.UpdatedAt.After(shown.UpdatedAt) {
+				shown.UpdatedAt = translation.UpdatedAt
+			}
+			return &shown
+		}
+	}
+	return b
+}
+
+func localizeBanners(banners []*Banner, chain []string) []*Banner {
+	if len(chain) == 0 {
+		return banners
+	}
+	localized := make([]*Banner, 0, len(banners))
+	for _, banner := range banners {
+		localized = append(localized, banner.localize(chain))
+	}
+	return localized
+}
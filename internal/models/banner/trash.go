@@ -0,0 +1,114 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"banner-service/pkg/logging"
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const purgeBatchSize = 100
+
+// GetTrash отдает список баннеров из корзины с теми же параметрами, что и GET /banner
+func (h *Handler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	h.getBanners(w, r, true)
+}
+
+// RestoreBannerHandler возвращает баннер из корзины
+func (h *Handler) RestoreBannerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = h.repository.RestoreBanner(ctx, id)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	// Восстановленный баннер снова участвует в показах на своих парах
+	h.invalidatePublished(ctx, id)
+
+	response := map[string]string{"message": fmt.Sprintf("banner with ID (id %d) restored", id)}
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+// Purger периодически окончательно удаляет баннеры, пролежавшие в корзине дольше retention
+type Purger struct {
+	repository Storage
+	logger     *logging.Logger
+	interval   time.Duration
+	retention  time.Duration
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+func NewPurger(repository Storage, logger *logging.Logger, interval, retention time.Duration) *Purger {
+	return &Purger{
+		repository: repository,
+		logger:     logger,
+		interval:   interval,
+		retention:  retention,
+	}
+}
+
+func (p *Purger) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.purge(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (p *Purger) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	p.wg.Wait()
+}
+
+// purge удаляет баннеры пачками, чтобы не держать долгих блокировок
+func (p *Purger) purge(ctx context.Context) {
+	before := time.Now().Add(-p.retention)
+	purged := 0
+	for ctx.Err() == nil {
+		batchCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		ids, err := p.repository.PurgeDeletedBanners(batchCtx, before, purgeBatchSize)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				p.logger.Errorf("trash purge failed: %v", err)
+			}
+			return
+		}
+		purged += len(ids)
+		if len(ids) < purgeBatchSize {
+			break
+		}
+	}
+	if purged > 0 {
+		p.logger.Infof("purged %d banners from trash", purged)
+	}
+}
@@ -0,0 +1,199 @@
+package banner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// maxCompiledSchemas ограничивает кэш скомпилированных схем
+const maxCompiledSchemas = 256
+
+// compiledSchemas хранит скомпилированные схемы фич по их тексту. Измененная схема
+// попадает в кэш под новым ключом, поэтому при обновлении фичи кэш сбрасывать не нужно.
+var compiledSchemas = struct {
+	sync.Mutex
+	schemas map[string]*jsonschema.Schema
+}{schemas: make(map[string]*jsonschema.Schema)}
+
+// FieldError описывает ошибку в конкретном поле баннера
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError содержит все найденные ошибки полей
+type ValidationError struct {
+	Fields []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// normalizeBanner приводит входные данные баннера к виду, в котором они хранятся
+func normalizeBanner(banner *Banner) error {
+	if string(banner.Content) == "null" {
+		banner.Content = nil
+	}
+	banner.Targeting = banner.Targeting.normalize()
+	err := banner.NormalizeSchedule()
+	if err != nil {
+		return &ValidationError{Fields: []FieldError{{Field: "timezone", Message: err.Error()}}}
+	}
+	return nil
+}
+
+// validateBanner проверяет обязательные поля баннера и его content по JSON Schema фичи
+func (h *Handler) validateBanner(ctx context.Context, banner Banner) error {
+	var fields []FieldError
+
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	err := validate.Struct(banner)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+		for _, fe := range validationErrors {
+			fields = append(fields, FieldError{Field: fieldPath(fe.Namespace()), Message: fmt.Sprintf("failed on the '%s' rule", fe.Tag())})
+		}
+	}
+
+	if banner.StartsAt != nil && banner.EndsAt != nil && !banner.EndsAt.After(*banner.StartsAt) {
+		fields = append(fields, FieldError{Field: "ends_at", Message: "must be after starts_at"})
+	}
+	fields = append(fields, banner.Targeting.validate()...)
+	fields = append(fields, banner.FrequencyCap.validate(banner.ID)...)
+
+	if banner.FeatureID.ID != 0 {
+		schema, err := h.repository.GetFeatureSchema(ctx, banner.FeatureID.ID)
+		if err != nil {
+			if !errors.Is(err, ErrFeatureNotFound) {
+				return err
+			}
+			fields = append(fields, FieldError{Field: "feature_id.id", Message: "feature does not exist"})
+		} else if schema != nil {
+			fields = append(fields, validateContent(schema, banner.Content)...)
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// validateContent проверяет content баннера по JSON Schema
+func validateContent(schema, content json.RawMessage) []FieldError {
+	if content == nil {
+		return []FieldError{{Field: "content", Message: "required by feature schema"}}
+	}
+
+	compiled, err := cachedSchema(schema)
+	if err != nil {
+		return []FieldError{{Field: "feature_id.schema", Message: err.Error()}}
+	}
+
+	var doc interface{}
+	err = json.Unmarshal(content, &doc)
+	if err != nil {
+		return []FieldError{{Field: "content", Message: "invalid JSON"}}
+	}
+
+	err = compiled.Validate(doc)
+	if err == nil {
+		return nil
+	}
+
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(err, &schemaErr) {
+		return []FieldError{{Field: "content", Message: err.Error()}}
+	}
+
+	var fields []FieldError
+	var collect func(ve *jsonschema.ValidationError)
+	collect = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			fields = append(fields, FieldError{Field: contentPath(ve.InstanceLocation), Message: ve.Message})
+			return
+		}
+		for _, cause := range ve.Causes {
+			collect(cause)
+		}
+	}
+	collect(schemaErr)
+
+	return fields
+}
+
+// compileSchema компилирует JSON Schema фичи
+func compileSchema(schema json.RawMessage) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	err := compiler.AddResource("feature.json", bytes.NewReader(schema))
+	if err != nil {
+		return nil, err
+	}
+	return compiler.Compile("feature.json")
+}
+
+// cachedSchema возвращает скомпилированную схему из кэша, компилируя ее при первом обращении
+func cachedSchema(schema json.RawMessage) (*jsonschema.Schema, error) {
+	key := string(schema)
+	compiledSchemas.Lock()
+	compiled, ok := compiledSchemas.schemas[key]
+	compiledSchemas.Unlock()
+	if ok {
+		return compiled, nil
+	}
+
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	compiledSchemas.Lock()
+	// Старые версии схем больше не запрашиваются, поэтому переполненный кэш просто очищается
+	if len(compiledSchemas.schemas) >= maxCompiledSchemas {
+		compiledSchemas.schemas = make(map[string]*jsonschema.Schema)
+	}
+	compiledSchemas.schemas[key] = compiled
+	compiledSchemas.Unlock()
+	return compiled, nil
+}
+
+// contentPath переводит JSON Pointer из ошибки схемы в путь вида content.cta.url
+func contentPath(pointer string) string {
+	if pointer == "" {
+		return "content"
+	}
+	return "content" + strings.ReplaceAll(pointer, "/", ".")
+}
+
+// fieldPath убирает имя структуры из пути поля валидатора
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,212 @@
+package banner
+
+import (
+	"banner-service/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	bannerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	variants, err := h.repository.GetVariants(ctx, bannerID)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, variants)
+}
+
+func (h *Handler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	bannerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	var variant Variant
+	err = json.NewDecoder(r.Body).Decode(&variant)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	variant.BannerID = bannerID
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = h.validateVariant(ctx, &variant)
+	if err == nil {
+		err = h.repository.CreateVariant(ctx, &variant)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	h.Cache.DeleteBannersByID(ctx, []int{bannerID})
+
+	utils.RespondJSON(w, http.StatusCreated, variant)
+}
+
+func (h *Handler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	bannerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+	if err != nil {
+		http.Error(w, "Invalid variant ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	var variant Variant
+	err = json.NewDecoder(r.Body).Decode(&variant)
+	if err != nil {
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	variant.ID = variantID
+	variant.BannerID = bannerID
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = h.validateVariant(ctx, &variant)
+	if err == nil {
+		err = h.repository.UpdateVariant(ctx, &variant)
+	}
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	h.Cache.DeleteBannersByID(ctx, []int{bannerID})
+
+	utils.RespondJSON(w, http.StatusOK, variant)
+}
+
+func (h *Handler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	bannerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+	if err != nil {
+		http.Error(w, "Invalid variant ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := getContextTimeout(r.Context())
+	defer cancel()
+
+	err = h.repository.DeleteVariant(ctx, bannerID, variantID)
+	if err != nil {
+		handleErrors(err, h.logger, w)
+		return
+	}
+	h.Cache.DeleteBannersByID(ctx, []int{bannerID})
+
+	response := map[string]string{"message": fmt.Sprintf("variant with ID (id %d) deleted", variantID)}
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+// validateVariant проверяет поля варианта и его content по JSON Schema фичи баннера
+func (h *Handler) validateVariant(ctx context.Context, variant *Variant) error {
+	if string(variant.Content) == "null" {
+		variant.Content = nil
+	}
+
+	var fields []FieldError
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	err := validate.Struct(variant)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+		for _, fe := range validationErrors {
+			fields = append(fields, FieldError{Field: fieldPath(fe.Namespace()), Message: fmt.Sprintf("failed on the '%s' rule", fe.Tag())})
+		}
+	}
+
+	if variant.Content != nil {
+		schema, err := h.repository.GetBannerSchema(ctx, variant.BannerID)
+		if err != nil {
+			return err
+		}
+		if schema != nil {
+			fields = append(fields, validateContent(schema, variant.Content)...)
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// pickVariant детерминированно выбирает вариант для пользователя пропорционально весам.
+// Пока набор вариантов и их веса не меняются, пользователь всегда видит один и тот же вариант.
+func pickVariant(variants []Variant, bannerID, userID int) *Variant {
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	// Идентификатор баннера в хэше делает распределения разных экспериментов независимыми
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%d:%d", bannerID, userID)
+	point := int(hash.Sum64() % uint64(total))
+
+	for i := range variants {
+		if point < variants[i].Weight {
+			return &variants[i]
+		}
+		point -= variants[i].Weight
+	}
+	return nil
+}
+
+// withVariant возвращает копию баннера с полями варианта; баннер из кэша не изменяется
+func (b *Banner) withVariant(variant *Variant) *Banner {
+	shown := *b
+	shown.VariantID = &variant.ID
+	if variant.Title != "" {
+		shown.Title = variant.Title
+	}
+	if variant.Text != "" {
+		shown.Text = variant.Text
+	}
+	if variant.URL != "" {
+		shown.URL = variant.URL
+	}
+	if variant.Content != nil {
+		shown.Content = variant.Content
+	}
+	if variant.UpdatedAt.After(shown.UpdatedAt) {
+		shown.UpdatedAt = variant.UpdatedAt
+	}
+	return &shown
+}
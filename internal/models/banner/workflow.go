@@ -0,0 +1,132 @@
+package banner
+
+import (
+	"banner-service/internal/models/user"
+	"banner-service/internal/utils"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Этапы согласования баннера. Любое изменение содержимого возвращает баннер в черновик;
+// пользователям отдается последняя опубликованная версия, пока не будет опубликована новая.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusApproved  = "approved"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+var statuses = []string{StatusDraft, StatusInReview, StatusApproved, StatusPublished, StatusArchived}
+
+// WorkflowState - состояние согласования последней версии баннера.
+// AuthorID - автор этой версии: одобрить или отклонить ее сам он не может.
+type WorkflowState struct {
+	BannerID         int       `json:"banner_id"`
+	Version          int       `json:"version"`
+	Status           string    `json:"status"`
+	PublishedVersion *int      `json:"published_version"`
+	AuthorID         *int      `json:"author_id"`
+	ReviewedBy       *int      `json:"reviewed_by"`
+	StatusChangedAt  time.Time `json:"status_changed_at"`
+}
+
+// TransitionError возвращается, когда переход недоступен из текущего статуса баннера
+type TransitionError struct {
+	Transition string
+	Status     string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot %s banner in status %s", e.Transition, e.Status)
+}
+
+type transition struct {
+	from []string
+	to   string
+	// review - переход выполняет рецензент, который не может быть автором версии
+	review bool
+}
+
+var transitions = map[string]transition{
+	"submit":    {from: []string{StatusDraft}, to: StatusInReview},
+	"approve":   {from: []string{StatusInReview}, to: StatusApproved, review: true},
+	"reject":    {from: []string{StatusInReview}, to: StatusDraft, review: true},
+	"publish":   {from: []string{StatusApproved}, to: StatusPublished},
+	"archive":   {from: []string{StatusDraft, StatusInReview, StatusApproved, StatusPublished}, to: StatusArchived},
+	"unarchive": {from: []string{StatusArchived}, to: StatusDraft},
+}
+
+func (t transition) apply(name string, state *WorkflowState, actorID int) error {
+	if !contains(t.from, state.Status) {
+		return &TransitionError{Transition: name, Status: state.Status}
+	}
+	if t.review {
+		if state.AuthorID != nil && *state.AuthorID == actorID {
+			return ErrSelfReview
+		}
+		state.ReviewedBy = &actorID
+	}
+	if t.to == StatusDraft && !t.review {
+		state.ReviewedBy = nil
+	}
+	state.Status = t.to
+	return nil
+}
+
+// Transition возвращает обработчик перехода name. Версию, к которой относится решение,
+// можно закрепить заголовком If-Match, чтобы не одобрить изменения, сделанные после просмотра.
+func (h *Handler) Transition(name string) http.HandlerFunc {
+	t, ok := transitions[name]
+	if !ok {
+		panic("unknown banner transition " + name)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+			return
+		}
+		claims, ok := user.FromContext(r.Context())
+		if !ok {
+			http.Error(w, "missing token cookie", http.StatusUnauthorized)
+			return
+		}
+
+		ctx, cancel := getContextTimeout(r.Context())
+		defer cancel()
+
+		version, err := h.ifMatchVersion(ctx, r, id)
+		if err != nil {
+			handleErrors(err, h.logger, w)
+			return
+		}
+
+		state, err := h.repository.ChangeStatus(ctx, id, version, func(state *WorkflowState) error {
+			return t.apply(name, state, claims.ID)
+		})
+		if err != nil {
+			handleErrors(err, h.logger, w)
+			return
+		}
+		// Публикация и архивация меняют версию, которую получают пользователи. Опубликованная
+		// версия может занять новые пары (фича, тег), закэшированные еще без баннера.
+		if t.to == StatusPublished || t.to == StatusArchived {
+			h.Cache.DeleteBannersByID(ctx, []int{id})
+		}
+		if t.to == StatusPublished {
+			h.invalidatePublished(ctx, id)
+		}
+
+		w.Header().Set("ETag", ETag(state.Version))
+		utils.RespondJSON(w, http.StatusOK, state)
+	}
+}
+
+func validStatus(status string) bool {
+	return contains(statuses, status)
+}
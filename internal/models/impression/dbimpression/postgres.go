@@ -0,0 +1,46 @@
+package dbimpression
+
+import (
+	"banner-service/internal/models/impression"
+	"banner-service/pkg/db/postgresql"
+	"banner-service/pkg/logging"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+type impressionRepository struct {
+	db     postgresql.Client
+	logger *logging.Logger
+}
+
+func NewImpressionRepository(db postgresql.Client, logger *logging.Logger) impression.Storage {
+	return &impressionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *impressionRepository) SaveBatch(ctx context.Context, impressions []impression.Impression) error {
+	columns := []string{"banner_id", "feature_id", "tag_id", "variant_id", "user_id", "shown_at"}
+	rows := make([][]any, 0, len(impressions))
+	for _, i := range impressions {
+		rows = append(rows, []any{i.BannerID, i.FeatureID, i.TagID, i.VariantID, i.UserID, i.ShownAt})
+	}
+
+	r.logger.Trace(fmt.Sprintf("COPY impressions (%d rows)", len(rows)))
+
+	_, err := r.db.CopyFrom(ctx, pgx.Identifier{"impressions"}, columns, pgx.CopyFromRows(rows))
+	return err
+}
+
+func (r *impressionRepository) SaveClick(ctx context.Context, click impression.Click) error {
+	query := `
+		INSERT INTO clicks (banner_id, feature_id, tag_id, variant_id, user_id, clicked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err := r.db.Exec(ctx, query, click.BannerID, click.FeatureID, click.TagID, click.VariantID, click.UserID, click.ClickedAt)
+	return err
+}
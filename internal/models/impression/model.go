@@ -0,0 +1,23 @@
+package impression
+
+import "time"
+
+// Impression - факт показа баннера пользователю
+type Impression struct {
+	BannerID  int       `json:"banner_id"`
+	FeatureID int       `json:"feature_id"`
+	TagID     int       `json:"tag_id"`
+	VariantID *int      `json:"variant_id,omitempty"`
+	UserID    *int      `json:"user_id,omitempty"`
+	ShownAt   time.Time `json:"shown_at"`
+}
+
+// Click - переход пользователя по ссылке баннера
+type Click struct {
+	BannerID  int       `json:"banner_id"`
+	FeatureID int       `json:"feature_id"`
+	TagID     int       `json:"tag_id"`
+	VariantID *int      `json:"variant_id,omitempty"`
+	UserID    *int      `json:"user_id,omitempty"`
+	ClickedAt time.Time `json:"clicked_at"`
+}
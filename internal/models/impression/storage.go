@@ -0,0 +1,9 @@
+package impression
+
+import "context"
+
+type Storage interface {
+	// SaveBatch сохраняет пачку показов одной операцией
+	SaveBatch(ctx context.Context, impressions []Impression) error
+	SaveClick(ctx context.Context, click Click) error
+}
@@ -0,0 +1,119 @@
+package impression
+
+import (
+	"banner-service/pkg/logging"
+	"context"
+	"sync"
+	"time"
+)
+
+const saveTimeout = 5 * time.Second
+
+// Tracker асинхронно накапливает показы и сохраняет их пачками,
+// чтобы запись в базу данных не задерживала ответ пользователю
+type Tracker struct {
+	repository    Storage
+	logger        *logging.Logger
+	events        chan Impression
+	batchSize     int
+	flushInterval time.Duration
+	quit          chan struct{}
+	wg            sync.WaitGroup
+
+	mutex   sync.Mutex
+	dropped int
+}
+
+func NewTracker(repository Storage, logger *logging.Logger, bufferSize, batchSize int, flushInterval time.Duration) *Tracker {
+	return &Tracker{
+		repository:    repository,
+		logger:        logger,
+		events:        make(chan Impression, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		quit:          make(chan struct{}),
+	}
+}
+
+// Track ставит показ в очередь и никогда не блокирует вызывающего.
+// Если буфер переполнен, показ отбрасывается.
+func (t *Tracker) Track(impression Impression) {
+	select {
+	case t.events <- impression:
+	default:
+		t.mutex.Lock()
+		t.dropped++
+		t.mutex.Unlock()
+	}
+}
+
+func (t *Tracker) Start() {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(t.flushInterval)
+		defer ticker.Stop()
+
+		batch := make([]Impression, 0, t.batchSize)
+		for {
+			select {
+			case impression := <-t.events:
+				batch = append(batch, impression)
+				if len(batch) >= t.batchSize {
+					batch = t.save(batch)
+				}
+			case <-ticker.C:
+				batch = t.save(batch)
+			case <-t.quit:
+				t.drain(batch)
+				return
+			}
+		}
+	}()
+}
+
+// Stop дожидается сохранения всех показов, поставленных в очередь до вызова.
+// Вызывается после остановки HTTP-сервера, когда новых показов уже не будет.
+func (t *Tracker) Stop() {
+	close(t.quit)
+	t.wg.Wait()
+}
+
+func (t *Tracker) drain(batch []Impression) {
+	for {
+		select {
+		case impression := <-t.events:
+			batch = append(batch, impression)
+			if len(batch) >= t.batchSize {
+				batch = t.save(batch)
+			}
+		default:
+			t.save(batch)
+			return
+		}
+	}
+}
+
+// save записывает пачку и возвращает пустой срез для следующей
+func (t *Tracker) save(batch []Impression) []Impression {
+	t.mutex.Lock()
+	dropped := t.dropped
+	t.dropped = 0
+	t.mutex.Unlock()
+	if dropped > 0 {
+		t.logger.Warnf("impression buffer is full, %d impressions dropped", dropped)
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+
+	err := t.repository.SaveBatch(ctx, batch)
+	if err != nil {
+		t.logger.Errorf("failed to save %d impressions: %v", len(batch), err)
+	}
+	return batch[:0]
+}
@@ -0,0 +1,114 @@
+package dbjob
+
+import (
+	"banner-service/internal/models/job"
+	"banner-service/pkg/db/postgresql"
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type jobRepository struct {
+	db     postgresql.Client
+	logger *logging.Logger
+}
+
+func NewJobRepository(db postgresql.Client, logger *logging.Logger) job.Storage {
+	return &jobRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *jobRepository) Create(ctx context.Context, j *job.Job) error {
+	query := `
+		INSERT INTO jobs (type, params)
+		VALUES ($1, $2)
+		RETURNING id, status, processed, created_at, updated_at`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	return r.db.QueryRow(ctx, query, j.Type, j.Params).Scan(&j.ID, &j.Status, &j.Processed, &j.CreatedAt, &j.UpdatedAt)
+}
+
+func (r *jobRepository) FindOne(ctx context.Context, id int) (*job.Job, error) {
+	query := `
+		SELECT id, type, params, status, processed, error, created_at, updated_at, finished_at
+		FROM jobs
+		WHERE id = $1`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	j, err := scanJob(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, job.ErrJobNotFound
+		}
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (r *jobRepository) Claim(ctx context.Context, lease time.Duration) (*job.Job, error) {
+	query := `
+		UPDATE jobs
+		SET status = 'running', locked_until = NOW() + make_interval(secs => $1), updated_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM jobs
+			WHERE status = 'pending' OR (status = 'running' AND locked_until < NOW())
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, type, params, status, processed, error, created_at, updated_at, finished_at`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	j, err := scanJob(r.db.QueryRow(ctx, query, lease.Seconds()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, job.ErrNoPendingJobs
+		}
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (r *jobRepository) UpdateProgress(ctx context.Context, id, processed int, lease time.Duration) error {
+	query := `
+		UPDATE jobs
+		SET processed = $2, locked_until = NOW() + make_interval(secs => $3), updated_at = NOW()
+		WHERE id = $1`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err := r.db.Exec(ctx, query, id, processed, lease.Seconds())
+	return err
+}
+
+func (r *jobRepository) Finish(ctx context.Context, id int, status string, errMessage *string) error {
+	query := `
+		UPDATE jobs
+		SET status = $2, error = $3, locked_until = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	_, err := r.db.Exec(ctx, query, id, status, errMessage)
+	return err
+}
+
+func scanJob(row pgx.Row) (*job.Job, error) {
+	var j job.Job
+	err := row.Scan(&j.ID, &j.Type, &j.Params, &j.Status, &j.Processed, &j.Error, &j.CreatedAt, &j.UpdatedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
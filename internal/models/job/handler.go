@@ -0,0 +1,55 @@
+package job
+
+import (
+	"banner-service/internal/utils"
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const contextTimeOut = time.Second * 10
+
+type Handler struct {
+	logger     *logging.Logger
+	repository Storage
+}
+
+func NewHandler(repository Storage, logger *logging.Logger) *Handler {
+	return &Handler{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid job ID parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), contextTimeOut)
+	defer cancel()
+
+	job, err := h.repository.FindOne(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			h.logger.Error(err)
+			http.Error(w, "request timeout", http.StatusRequestTimeout)
+			return
+		}
+		h.logger.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, job)
+}
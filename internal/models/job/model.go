@@ -0,0 +1,25 @@
+package job
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+type Job struct {
+	ID         int             `json:"id"`
+	Type       string          `json:"type"`
+	Params     json.RawMessage `json:"params"`
+	Status     string          `json:"status"`
+	Processed  int             `json:"processed"`
+	Error      *string         `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,124 @@
+package job
+
+import (
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const leaseDuration = time.Minute
+
+// Task выполняет задачу определенного типа. Задача должна быть идемпотентной:
+// после перезапуска сервера она запускается повторно с сохраненным job.Processed.
+type Task func(ctx context.Context, job *Job, progress func(processed int) error) error
+
+// Runner выполняет фоновые задачи, сохраненные в базе данных
+type Runner struct {
+	repository Storage
+	logger     *logging.Logger
+	interval   time.Duration
+	tasks      map[string]Task
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+func NewRunner(repository Storage, logger *logging.Logger, interval time.Duration) *Runner {
+	return &Runner{
+		repository: repository,
+		logger:     logger,
+		interval:   interval,
+		tasks:      make(map[string]Task),
+	}
+}
+
+// Register связывает тип задачи с ее обработчиком. Вызывается до Start.
+func (r *Runner) Register(jobType string, task Task) {
+	r.tasks[jobType] = task
+}
+
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			r.runPending(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop прерывает текущую задачу и дожидается завершения воркера.
+// Прерванная задача будет продолжена после истечения lease.
+func (r *Runner) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Runner) runPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := r.repository.Claim(ctx, leaseDuration)
+		if err != nil {
+			if !errors.Is(err, ErrNoPendingJobs) && ctx.Err() == nil {
+				r.logger.Error(err)
+			}
+			return
+		}
+		r.run(ctx, job)
+	}
+}
+
+func (r *Runner) run(ctx context.Context, job *Job) {
+	r.logger.Infof("running job %d (%s)", job.ID, job.Type)
+
+	task, ok := r.tasks[job.Type]
+	if !ok {
+		r.finish(job, StatusFailed, fmt.Errorf("unknown job type %q", job.Type))
+		return
+	}
+
+	err := task(ctx, job, func(processed int) error {
+		return r.repository.UpdateProgress(ctx, job.ID, processed, leaseDuration)
+	})
+	if ctx.Err() != nil {
+		// Сервер останавливается: задача останется в статусе running и будет подхвачена снова
+		r.logger.Infof("job %d interrupted", job.ID)
+		return
+	}
+	if err != nil {
+		r.finish(job, StatusFailed, err)
+		return
+	}
+	r.finish(job, StatusDone, nil)
+}
+
+func (r *Runner) finish(job *Job, status string, jobErr error) {
+	var message *string
+	if jobErr != nil {
+		r.logger.Errorf("job %d failed: %v", job.ID, jobErr)
+		text := jobErr.Error()
+		message = &text
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), contextTimeOut)
+	defer cancel()
+
+	err := r.repository.Finish(ctx, job.ID, status, message)
+	if err != nil {
+		r.logger.Error(err)
+	}
+}
@@ -0,0 +1,22 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrJobNotFound   = errors.New("job not found")
+	ErrNoPendingJobs = errors.New("no pending jobs")
+)
+
+type Storage interface {
+	Create(ctx context.Context, job *Job) error
+	FindOne(ctx context.Context, id int) (*Job, error)
+	// Claim захватывает следующую задачу на время lease. Задачи, чей lease истек
+	// (например, после перезапуска сервера), захватываются повторно.
+	Claim(ctx context.Context, lease time.Duration) (*Job, error)
+	UpdateProgress(ctx context.Context, id, processed int, lease time.Duration) error
+	Finish(ctx context.Context, id int, status string, errMessage *string) error
+}
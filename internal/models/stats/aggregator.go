@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"banner-service/pkg/logging"
+	"context"
+	"sync"
+	"time"
+)
+
+// Aggregator периодически переносит сырые показы и клики в почасовые агрегаты
+type Aggregator struct {
+	repository Storage
+	logger     *logging.Logger
+	interval   time.Duration
+	// lag - запас на события, которые еще лежат в буфере трекера показов
+	lag time.Duration
+	// lookback - сколько уже агрегированных часов пересчитывается, чтобы учесть опоздавшие события
+	lookback time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewAggregator(repository Storage, logger *logging.Logger, interval, lag, lookback time.Duration) *Aggregator {
+	return &Aggregator{
+		repository: repository,
+		logger:     logger,
+		interval:   interval,
+		lag:        lag,
+		lookback:   lookback,
+	}
+}
+
+func (a *Aggregator) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			a.rollup(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (a *Aggregator) Stop() {
+	if a.cancel == nil {
+		return
+	}
+	a.cancel()
+	a.wg.Wait()
+}
+
+// rollup агрегирует только завершившиеся часы. События, которые трекер записал позже
+// (например, после недоступности базы), учитываются при пересчете последних lookback часов.
+func (a *Aggregator) rollup(ctx context.Context) {
+	until := time.Now().Add(-a.lag).Truncate(time.Hour)
+	since := until.Add(-a.lookback)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	watermark, err := a.repository.Rollup(ctx, since, until)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.logger.Errorf("stats rollup failed: %v", err)
+		}
+		return
+	}
+	a.logger.Tracef("stats rolled up to %s", watermark.Format(time.RFC3339))
+}
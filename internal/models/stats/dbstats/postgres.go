@@ -0,0 +1,190 @@
+package dbstats
+
+import (
+	"banner-service/internal/models/stats"
+	"banner-service/pkg/db/postgresql"
+	"banner-service/pkg/logging"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type statsRepository struct {
+	db     postgresql.Client
+	logger *logging.Logger
+}
+
+func NewStatsRepository(db postgresql.Client, logger *logging.Logger) stats.Storage {
+	return &statsRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *statsRepository) GetReport(ctx context.Context, filter stats.Filter) (*stats.Report, error) {
+	report := &stats.Report{From: filter.From, To: filter.To, Granularity: filter.Granularity, Series: make([]stats.Point, 0)}
+
+	// Уникальных пользователей нельзя сложить по часам, поэтому они считаются
+	// по отдельному агрегату с парами (час, пользователь)
+	args := []interface{}{filter.Granularity}
+	where := conditions(filter, &args)
+	query := `
+		WITH counts AS (
+			SELECT date_trunc($1, hour, 'UTC') AS bucket, SUM(impressions) AS impressions, SUM(clicks) AS clicks
+			FROM banner_stats_hourly` + where + `
+			GROUP BY bucket
+		), users AS (
+			SELECT date_trunc($1, hour, 'UTC') AS bucket, COUNT(DISTINCT user_id) AS unique_users
+			FROM banner_stats_hourly_users` + where + `
+			GROUP BY bucket
+		)
+		SELECT c.bucket, c.impressions, c.clicks, COALESCE(u.unique_users, 0)
+		FROM counts c
+		LEFT JOIN users u ON u.bucket = c.bucket
+		ORDER BY c.bucket`
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var point stats.Point
+		err = rows.Scan(&point.Time, &point.Impressions, &point.Clicks, &point.UniqueUsers)
+		if err != nil {
+			return nil, err
+		}
+		point.Time = point.Time.UTC()
+		point.CTR = ctr(point.Clicks, point.Impressions)
+		report.Series = append(report.Series, point)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	args = nil
+	where = conditions(filter, &args)
+	query = `
+		SELECT COALESCE(SUM(impressions), 0), COALESCE(SUM(clicks), 0),
+		       (SELECT COUNT(DISTINCT user_id) FROM banner_stats_hourly_users` + where + `),
+		       (SELECT rolled_up_to FROM stats_rollup_state WHERE id = 1)
+		FROM banner_stats_hourly` + where
+
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	totals := &report.Totals
+	err = r.db.QueryRow(ctx, query, args...).Scan(&totals.Impressions, &totals.Clicks, &totals.UniqueUsers, &report.CompleteUntil)
+	if err != nil {
+		return nil, err
+	}
+	totals.CTR = ctr(totals.Clicks, totals.Impressions)
+	report.CompleteUntil = report.CompleteUntil.UTC()
+
+	return report, nil
+}
+
+// conditions строит условие WHERE по фильтру, дописывая значения параметров в args
+func conditions(filter stats.Filter, args *[]interface{}) string {
+	arg := func(value interface{}) string {
+		*args = append(*args, value)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	where := fmt.Sprintf(" WHERE hour >= %s AND hour < %s", arg(filter.From), arg(filter.To))
+	if filter.BannerID != nil {
+		where += " AND banner_id = " + arg(*filter.BannerID)
+	}
+	if filter.FeatureID != nil {
+		where += " AND feature_id = " + arg(*filter.FeatureID)
+	}
+	if filter.TagID != nil {
+		where += " AND tag_id = " + arg(*filter.TagID)
+	}
+	if filter.VariantID != nil {
+		where += " AND variant_id = " + arg(*filter.VariantID)
+	}
+	return where
+}
+
+func (r *statsRepository) Rollup(ctx context.Context, since, until time.Time) (time.Time, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокировка отметки не дает двум экземплярам сервиса агрегировать одни и те же события
+	query := `SELECT rolled_up_to FROM stats_rollup_state WHERE id = 1 FOR UPDATE`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+
+	var watermark time.Time
+	err = tx.QueryRow(ctx, query).Scan(&watermark)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// События, записанные с опозданием, попадают в уже агрегированные часы,
+	// поэтому часы начиная с since пересчитываются заново, а не дополняются
+	from := since
+	if watermark.Before(from) {
+		from = watermark
+	}
+	if !from.Before(until) {
+		return watermark, nil
+	}
+
+	queries := []string{`
+		DELETE FROM banner_stats_hourly WHERE hour >= $1 AND hour < $2`, `
+		DELETE FROM banner_stats_hourly_users WHERE hour >= $1 AND hour < $2`, `
+		INSERT INTO banner_stats_hourly (banner_id, feature_id, tag_id, variant_id, hour, impressions)
+		SELECT banner_id, feature_id, tag_id, COALESCE(variant_id, 0), date_trunc('hour', shown_at, 'UTC'), COUNT(*)
+		FROM impressions
+		WHERE shown_at >= $1 AND shown_at < $2
+		GROUP BY 1, 2, 3, 4, 5`, `
+		INSERT INTO banner_stats_hourly (banner_id, feature_id, tag_id, variant_id, hour, clicks)
+		SELECT banner_id, feature_id, tag_id, COALESCE(variant_id, 0), date_trunc('hour', clicked_at, 'UTC'), COUNT(*)
+		FROM clicks
+		WHERE clicked_at >= $1 AND clicked_at < $2
+		GROUP BY 1, 2, 3, 4, 5
+		ON CONFLICT (banner_id, feature_id, tag_id, variant_id, hour)
+		DO UPDATE SET clicks = EXCLUDED.clicks`, `
+		INSERT INTO banner_stats_hourly_users (banner_id, feature_id, tag_id, variant_id, hour, user_id)
+		SELECT DISTINCT banner_id, feature_id, tag_id, COALESCE(variant_id, 0), date_trunc('hour', shown_at, 'UTC'), user_id
+		FROM impressions
+		WHERE shown_at >= $1 AND shown_at < $2 AND user_id IS NOT NULL`,
+	}
+	for _, query = range queries {
+		r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+		_, err = tx.Exec(ctx, query, from, until)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if until.After(watermark) {
+		query = `UPDATE stats_rollup_state SET rolled_up_to = $1 WHERE id = 1`
+		r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
+		_, err = tx.Exec(ctx, query, until)
+		if err != nil {
+			return time.Time{}, err
+		}
+		watermark = until
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return watermark, nil
+}
+
+func ctr(clicks, impressions int64) float64 {
+	if impressions == 0 {
+		return 0
+	}
+	return float64(clicks) / float64(impressions)
+}
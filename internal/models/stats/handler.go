@@ -0,0 +1,137 @@
+package stats
+
+import (
+	"banner-service/internal/utils"
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	contextTimeOut = time.Second * 10
+	defaultPeriod  = 7 * 24 * time.Hour
+	maxPoints      = 2000
+)
+
+type Handler struct {
+	logger     *logging.Logger
+	repository Storage
+}
+
+func NewHandler(repository Storage, logger *logging.Logger) *Handler {
+	return &Handler{
+		logger:     logger,
+		repository: repository,
+	}
+}
+
+// GetStats отдает статистику с фильтрами feature_id, tag_id, variant_id, from, to и granularity
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.respondReport(w, r, filter)
+}
+
+// GetBannerStats отдает статистику одного баннера
+func (h *Handler) GetBannerStats(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid banner ID parameter", http.StatusBadRequest)
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.BannerID = &id
+	h.respondReport(w, r, filter)
+}
+
+func (h *Handler) respondReport(w http.ResponseWriter, r *http.Request, filter Filter) {
+	ctx, cancel := context.WithTimeout(r.Context(), contextTimeOut)
+	defer cancel()
+
+	report, err := h.repository.GetReport(ctx, filter)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			h.logger.Error(err)
+			http.Error(w, "request timeout", http.StatusRequestTimeout)
+			return
+		}
+		h.logger.Error(err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, report)
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	var filter Filter
+	var err error
+
+	filter.FeatureID, err = utils.ParseOptionalInt(query.Get("feature_id"))
+	if err != nil {
+		return filter, errors.New("invalid feature_id parameter")
+	}
+	filter.TagID, err = utils.ParseOptionalInt(query.Get("tag_id"))
+	if err != nil {
+		return filter, errors.New("invalid tag_id parameter")
+	}
+	filter.VariantID, err = utils.ParseOptionalInt(query.Get("variant_id"))
+	if err != nil {
+		return filter, errors.New("invalid variant_id parameter")
+	}
+
+	filter.To = time.Now()
+	if value := query.Get("to"); value != "" {
+		filter.To, err = utils.ParseTime(value)
+		if err != nil {
+			return filter, errors.New("invalid to parameter")
+		}
+	}
+	filter.From = filter.To.Add(-defaultPeriod)
+	if value := query.Get("from"); value != "" {
+		filter.From, err = utils.ParseTime(value)
+		if err != nil {
+			return filter, errors.New("invalid from parameter")
+		}
+	}
+	// Агрегаты почасовые, поэтому границы периода выравниваются по часам
+	filter.From = filter.From.UTC().Truncate(time.Hour)
+	if to := filter.To.UTC().Truncate(time.Hour); to.Before(filter.To) {
+		filter.To = to.Add(time.Hour)
+	} else {
+		filter.To = to
+	}
+	if !filter.To.After(filter.From) {
+		return filter, errors.New("from must be before to")
+	}
+
+	filter.Granularity = query.Get("granularity")
+	step := 24 * time.Hour
+	switch filter.Granularity {
+	case "":
+		filter.Granularity = GranularityDay
+	case GranularityDay:
+	case GranularityHour:
+		step = time.Hour
+	default:
+		return filter, errors.New("granularity must be hour or day")
+	}
+	if filter.To.Sub(filter.From)/step > maxPoints {
+		return filter, fmt.Errorf("period is too long for granularity %s", filter.Granularity)
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,45 @@
+package stats
+
+import "time"
+
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// Filter задает срез статистики. Границы периода выравниваются по часам.
+type Filter struct {
+	BannerID  *int
+	FeatureID *int
+	TagID     *int
+	// VariantID 0 выбирает показы без варианта A/B-теста
+	VariantID   *int
+	From        time.Time
+	To          time.Time
+	Granularity string
+}
+
+// Counters - показатели баннеров за период
+type Counters struct {
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	UniqueUsers int64   `json:"unique_users"`
+	CTR         float64 `json:"ctr"`
+}
+
+// Point - показатели за один час или день
+type Point struct {
+	Time time.Time `json:"time"`
+	Counters
+}
+
+// Report - статистика за период с разбивкой по granularity.
+// CompleteUntil - момент, до которого события уже агрегированы.
+type Report struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	Granularity   string    `json:"granularity"`
+	Totals        Counters  `json:"totals"`
+	Series        []Point   `json:"series"`
+	CompleteUntil time.Time `json:"complete_until"`
+}
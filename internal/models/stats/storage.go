@@ -0,0 +1,14 @@
+package stats
+
+import (
+	"context"
+	"time"
+)
+
+type Storage interface {
+	// GetReport собирает статистику из почасовых агрегатов
+	GetReport(ctx context.Context, filter Filter) (*Report, error)
+	// Rollup заново агрегирует события от since (или от сохраненной отметки, если она раньше)
+	// до until и возвращает новую отметку
+	Rollup(ctx context.Context, since, until time.Time) (time.Time, error)
+}
@@ -0,0 +1,50 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/url"
+)
+
+var (
+	ErrMissingToken = errors.New("missing token cookie")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+type claimsKey struct{}
+
+// NewContext возвращает копию контекста с данными пользователя из JWT
+func NewContext(ctx context.Context, claims *CustomClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext достает данные пользователя, сохраненные middleware
+func FromContext(ctx context.Context) (*CustomClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*CustomClaims)
+	return claims, ok
+}
+
+// ParseToken разбирает и проверяет JWT из куки "token"
+func ParseToken(r *http.Request) (*CustomClaims, error) {
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		return nil, ErrMissingToken
+	}
+
+	tokenString, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims CustomClaims
+	_, err = jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte("mysecretkey"), nil
+	})
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &claims, nil
+}
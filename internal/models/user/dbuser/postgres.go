@@ -1,13 +1,20 @@
 package dbuser
 
 import (
+	"banner-service/internal/models/audit"
+	"banner-service/internal/models/audit/dbaudit"
 	"banner-service/internal/models/user"
 	"banner-service/pkg/db/postgresql"
 	"banner-service/pkg/logging"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 )
 
+// userSnapshot - состояние пользователя для журнала изменений, без хэша пароля
+const userSnapshot = `SELECT to_jsonb(u) - 'password' FROM "user" u WHERE u.id = $1 FOR UPDATE`
+
 type userRepository struct {
 	db     postgresql.Client
 	logger *logging.Logger
@@ -21,16 +28,27 @@ func NewUserRepository(db postgresql.Client, logger *logging.Logger) user.Storag
 }
 
 func (r *userRepository) Create(ctx context.Context, user *user.User) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	q := `INSERT INTO "user" (name, email, password, is_admin) VALUES ($1, $2, $3, $4) RETURNING id`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", q))
 
-	err := r.db.QueryRow(ctx, q, user.Name, user.Email, user.Password, user.IsAdmin).Scan(&user.ID)
+	err = tx.QueryRow(ctx, q, user.Name, user.Email, user.Password, user.IsAdmin).Scan(&user.ID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = dbaudit.Record(ctx, tx, audit.ActionCreate, audit.EntityUser, user.ID, nil, userSnapshot, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *userRepository) FindAll(ctx context.Context) ([]user.User, error) {
@@ -93,16 +111,39 @@ func (r *userRepository) FindOneByEmail(ctx context.Context, email string) (*use
 }
 
 func (r *userRepository) Delete(ctx context.Context, id string) (int64, error) {
-	query := `DELETE FROM "user" WHERE id=$1`
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := dbaudit.Snapshot(ctx, tx, userSnapshot, id)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `DELETE FROM "user" WHERE id=$1 RETURNING id`
 
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", query))
 
-	result, err := r.db.Exec(ctx, query, id)
+	var userID int
+	err = tx.QueryRow(ctx, query, id).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	err = dbaudit.Write(ctx, tx, &audit.Entry{Action: audit.ActionDelete, Entity: audit.EntityUser, EntityID: userID, Before: before})
 	if err != nil {
 		return 0, err
 	}
 
-	rowsAffected := result.RowsAffected()
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
 
-	return rowsAffected, nil
+	return 1, nil
 }
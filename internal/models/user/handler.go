@@ -55,7 +55,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 
 	user.Password = string(hashedPassword)
 
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeOut)
+	ctx, cancel := context.WithTimeout(r.Context(), contextTimeOut)
 	defer cancel()
 
 	err = h.repository.Create(ctx, &user)
@@ -89,7 +89,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeOut)
+	ctx, cancel := context.WithTimeout(r.Context(), contextTimeOut)
 	defer cancel()
 
 	user, err := h.repository.FindOneByEmail(ctx, userCredentials.Email)
@@ -133,7 +133,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeOut)
+	ctx, cancel := context.WithTimeout(r.Context(), contextTimeOut)
 	defer cancel()
 
 	users, err := h.repository.FindAll(ctx)
@@ -154,7 +154,7 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeOut)
+	ctx, cancel := context.WithTimeout(r.Context(), contextTimeOut)
 	defer cancel()
 
 	user, err := h.repository.FindOne(ctx, id)
@@ -175,7 +175,7 @@ func (h *Handler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeOut)
+	ctx, cancel := context.WithTimeout(r.Context(), contextTimeOut)
 	defer cancel()
 
 	rowsAffected, err := h.repository.Delete(ctx, id)
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"strconv"
+	"time"
+)
+
+// ParseOptionalInt разбирает необязательный числовой параметр запроса; пустое значение дает nil
+func ParseOptionalInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// ParseTime принимает время в RFC 3339 или дату вида 2024-04-14
+func ParseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// ParseOptionalTime разбирает необязательный параметр времени, как ParseTime; пустое значение дает nil
+func ParseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := ParseTime(value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
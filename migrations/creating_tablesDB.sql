@@ -1,3 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TABLE "user"
 (
     id       SERIAL PRIMARY KEY,
@@ -16,23 +18,82 @@ CREATE TABLE tags
 
 CREATE TABLE features
 (
-    id   SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL UNIQUE
+    id     SERIAL PRIMARY KEY,
+    name   VARCHAR(255) NOT NULL UNIQUE,
+    schema JSONB
+);
+
+
+CREATE TABLE templates
+(
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(255)             NOT NULL UNIQUE,
+    title      VARCHAR(255)             NOT NULL,
+    text       TEXT                     NOT NULL,
+    url        VARCHAR(255)             NOT NULL,
+    content    JSONB,
+    variables  JSONB                    NOT NULL DEFAULT '[]',
+    version    INTEGER                  NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+
+-- Версии шаблона неизменяемы: баннеры ссылаются на версию, из которой созданы
+CREATE TABLE template_versions
+(
+    template_id INTEGER                  NOT NULL REFERENCES templates (id) ON DELETE CASCADE,
+    version     INTEGER                  NOT NULL,
+    title       VARCHAR(255)             NOT NULL,
+    text        TEXT                     NOT NULL,
+    url         VARCHAR(255)             NOT NULL,
+    content     JSONB,
+    variables   JSONB                    NOT NULL,
+    author_id   INTEGER,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (template_id, version)
 );
 
 
 CREATE TABLE banners
 (
     id         SERIAL PRIMARY KEY,
-    title      VARCHAR(255) UNIQUE      NOT NULL,
+    title      VARCHAR(255)             NOT NULL,
     text       TEXT                     NOT NULL,
     url        VARCHAR(255)             NOT NULL,
+    content    JSONB,
     is_active  BOOLEAN                  NOT NULL DEFAULT false,
     feature_id INTEGER                  NOT NULL REFERENCES features (id),
+    starts_at  TIMESTAMP WITH TIME ZONE,
+    ends_at    TIMESTAMP WITH TIME ZONE,
+    targeting  JSONB,
+    frequency_cap JSONB,
+    timezone   VARCHAR(64)              NOT NULL DEFAULT 'UTC',
+    version           INTEGER                  NOT NULL DEFAULT 1,
+    published_version INTEGER,
+    status     VARCHAR(16)              NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'in_review', 'approved', 'published', 'archived')),
+    reviewed_by INTEGER,
+    status_changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    template_id       INTEGER,
+    template_version  INTEGER,
+    template_variables JSONB,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', text), 'B')
+    ) STORED,
+    FOREIGN KEY (template_id, template_version) REFERENCES template_versions (template_id, version)
 );
 
+-- Заголовок уникален только среди баннеров вне корзины
+CREATE UNIQUE INDEX banners_title_key ON banners (title) WHERE deleted_at IS NULL;
+CREATE INDEX banners_deleted_at_idx ON banners (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX banners_search_vector_idx ON banners USING GIN (search_vector);
+CREATE INDEX banners_title_trgm_idx ON banners USING GIN (title gin_trgm_ops);
+CREATE INDEX banners_text_trgm_idx ON banners USING GIN (text gin_trgm_ops);
+
 
 CREATE TABLE banner_tags
 (
@@ -40,3 +101,173 @@ CREATE TABLE banner_tags
     tag_id    INTEGER REFERENCES tags (id)    NOT NULL,
     PRIMARY KEY (banner_id, tag_id)
 );
+
+
+CREATE TABLE banner_revisions
+(
+    banner_id     INTEGER                  NOT NULL REFERENCES banners (id) ON DELETE CASCADE,
+    version       INTEGER                  NOT NULL,
+    title         VARCHAR(255)             NOT NULL,
+    text          TEXT                     NOT NULL,
+    url           VARCHAR(255)             NOT NULL,
+    content       JSONB,
+    is_active     BOOLEAN                  NOT NULL,
+    feature_id    INTEGER                  NOT NULL,
+    tag_ids       INTEGER[]                NOT NULL,
+    starts_at     TIMESTAMP WITH TIME ZONE,
+    ends_at       TIMESTAMP WITH TIME ZONE,
+    timezone      VARCHAR(64)              NOT NULL DEFAULT 'UTC',
+    targeting     JSONB,
+    frequency_cap JSONB,
+    variants      JSONB                    NOT NULL DEFAULT '[]',
+    translations  JSONB                    NOT NULL DEFAULT '[]',
+    author_id     INTEGER,
+    restored_from INTEGER,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (banner_id, version)
+);
+
+
+CREATE TABLE banner_variants
+(
+    id         SERIAL PRIMARY KEY,
+    banner_id  INTEGER                  NOT NULL REFERENCES banners (id) ON DELETE CASCADE,
+    name       VARCHAR(255)             NOT NULL,
+    title      VARCHAR(255)             NOT NULL DEFAULT '',
+    text       TEXT                     NOT NULL DEFAULT '',
+    url        VARCHAR(255)             NOT NULL DEFAULT '',
+    content    JSONB,
+    weight     INTEGER                  NOT NULL CHECK (weight > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX banner_variants_banner_id_idx ON banner_variants (banner_id);
+
+CREATE TABLE banner_translations
+(
+    banner_id  INTEGER                  NOT NULL REFERENCES banners (id) ON DELETE CASCADE,
+    locale     VARCHAR(35)              NOT NULL,
+    title      VARCHAR(255)             NOT NULL,
+    text       TEXT                     NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (banner_id, locale)
+);
+
+
+CREATE TABLE jobs
+(
+    id           SERIAL PRIMARY KEY,
+    type         VARCHAR(64)              NOT NULL,
+    params       JSONB                    NOT NULL,
+    status       VARCHAR(16)              NOT NULL DEFAULT 'pending',
+    processed    INTEGER                  NOT NULL DEFAULT 0,
+    error        TEXT,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at  TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX jobs_status_idx ON jobs (status);
+
+
+CREATE TABLE impressions
+(
+    id         BIGSERIAL PRIMARY KEY,
+    banner_id  INTEGER                  NOT NULL,
+    feature_id INTEGER                  NOT NULL,
+    tag_id     INTEGER                  NOT NULL,
+    variant_id INTEGER,
+    user_id    INTEGER,
+    shown_at   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX impressions_shown_at_idx ON impressions (shown_at);
+
+
+CREATE TABLE clicks
+(
+    id         BIGSERIAL PRIMARY KEY,
+    banner_id  INTEGER                  NOT NULL,
+    feature_id INTEGER                  NOT NULL,
+    tag_id     INTEGER                  NOT NULL,
+    variant_id INTEGER,
+    user_id    INTEGER,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX clicks_clicked_at_idx ON clicks (clicked_at);
+
+
+-- Почасовые агрегаты показов и кликов, заполняются фоновой агрегацией
+CREATE TABLE banner_stats_hourly
+(
+    banner_id   INTEGER                  NOT NULL,
+    feature_id  INTEGER                  NOT NULL,
+    tag_id      INTEGER                  NOT NULL,
+    variant_id  INTEGER                  NOT NULL DEFAULT 0,
+    hour        TIMESTAMP WITH TIME ZONE NOT NULL,
+    impressions BIGINT                   NOT NULL DEFAULT 0,
+    clicks      BIGINT                   NOT NULL DEFAULT 0,
+    PRIMARY KEY (banner_id, feature_id, tag_id, variant_id, hour)
+);
+
+CREATE INDEX banner_stats_hourly_hour_idx ON banner_stats_hourly (hour);
+
+
+CREATE TABLE banner_stats_hourly_users
+(
+    banner_id  INTEGER                  NOT NULL,
+    feature_id INTEGER                  NOT NULL,
+    tag_id     INTEGER                  NOT NULL,
+    variant_id INTEGER                  NOT NULL DEFAULT 0,
+    hour       TIMESTAMP WITH TIME ZONE NOT NULL,
+    user_id    INTEGER                  NOT NULL,
+    PRIMARY KEY (banner_id, feature_id, tag_id, variant_id, hour, user_id)
+);
+
+CREATE INDEX banner_stats_hourly_users_hour_idx ON banner_stats_hourly_users (hour);
+
+
+-- Момент, до которого события уже перенесены в агрегаты
+CREATE TABLE stats_rollup_state
+(
+    id           INTEGER PRIMARY KEY,
+    rolled_up_to TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+INSERT INTO stats_rollup_state (id, rolled_up_to) VALUES (1, 'epoch');
+
+
+-- Счетчики показов для лимита частоты при общем хранилище (frequency_store: postgres)
+CREATE TABLE frequency_counters
+(
+    key      VARCHAR(64) PRIMARY KEY,
+    count    INTEGER                  NOT NULL,
+    reset_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX frequency_counters_reset_at_idx ON frequency_counters (reset_at);
+
+
+-- Журнал изменений; пишется в той же транзакции, что и само изменение.
+-- actor_id без внешнего ключа: записи остаются после удаления пользователя
+CREATE TABLE audit_log
+(
+    id         BIGSERIAL PRIMARY KEY,
+    actor_id   INTEGER,
+    action     VARCHAR(32)              NOT NULL,
+    entity     VARCHAR(32)              NOT NULL,
+    entity_id  INTEGER                  NOT NULL,
+    before     JSONB,
+    after      JSONB,
+    request_id VARCHAR(64),
+    ip         INET,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_log_actor_idx ON audit_log (actor_id, id);
+CREATE INDEX audit_log_entity_idx ON audit_log (entity, entity_id, id);
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
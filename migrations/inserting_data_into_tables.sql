@@ -14,17 +14,17 @@ VALUES ('Feature 1'),
        ('Feature 5');
 
 
-INSERT INTO banners (title, text, url, is_active, feature_id)
-VALUES ('Banner 1', 'This is the text of Banner 1', 'https://example.com/banner1', true, 1),
-       ('Banner 2', 'This is the text of Banner 2', 'https://example.com/banner2', false, 1),
-       ('Banner 3', 'This is the text of Banner 3', 'https://example.com/banner3', true, 2),
-       ('Banner 4', 'This is the text of Banner 4', 'https://example.com/banner4', false, 2),
-       ('Banner 5', 'This is the text of Banner 5', 'https://example.com/banner5', true, 3),
-       ('Banner 6', 'This is the text of Banner 6', 'https://example.com/banner6', false, 3),
-       ('Banner 7', 'This is the text of Banner 7', 'https://example.com/banner7', true, 4),
-       ('Banner 8', 'This is the text of Banner 8', 'https://example.com/banner8', false, 4),
-       ('Banner 9', 'This is the text of Banner 9', 'https://example.com/banner9', true, 5),
-       ('Banner 10', 'This is the text of Banner 10', 'https://example.com/banner10', false, 5);
+INSERT INTO banners (title, text, url, is_active, feature_id, status, published_version)
+VALUES ('Banner 1', 'This is the text of Banner 1', 'https://example.com/banner1', true, 1, 'published', 1),
+       ('Banner 2', 'This is the text of Banner 2', 'https://example.com/banner2', false, 1, 'published', 1),
+       ('Banner 3', 'This is the text of Banner 3', 'https://example.com/banner3', true, 2, 'published', 1),
+       ('Banner 4', 'This is the text of Banner 4', 'https://example.com/banner4', false, 2, 'published', 1),
+       ('Banner 5', 'This is the text of Banner 5', 'https://example.com/banner5', true, 3, 'published', 1),
+       ('Banner 6', 'This is the text of Banner 6', 'https://example.com/banner6', false, 3, 'published', 1),
+       ('Banner 7', 'This is the text of Banner 7', 'https://example.com/banner7', true, 4, 'published', 1),
+       ('Banner 8', 'This is the text of Banner 8', 'https://example.com/banner8', false, 4, 'published', 1),
+       ('Banner 9', 'This is the text of Banner 9', 'https://example.com/banner9', true, 5, 'published', 1),
+       ('Banner 10', 'This is the text of Banner 10', 'https://example.com/banner10', false, 5, 'published', 1);
 
 
 INSERT INTO banner_tags (banner_id, tag_id)
@@ -38,3 +38,10 @@ VALUES (1, 1),
        (8, 3),
        (9, 4),
        (10, 5);
+
+
+INSERT INTO banner_revisions (banner_id, version, title, text, url, is_active, feature_id, tag_ids)
+SELECT b.id, b.version, b.title, b.text, b.url, b.is_active, b.feature_id, array_agg(bt.tag_id ORDER BY bt.tag_id)
+FROM banners b
+         JOIN banner_tags bt ON b.id = bt.banner_id
+GROUP BY b.id;
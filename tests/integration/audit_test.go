@@ -0,0 +1,68 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/audit"
+	"banner-service/pkg/logging"
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuditMiddlewareStoresRequest(t *testing.T) {
+	var request audit.Request
+	var ok bool
+	handler := middleware.RequestID(middleware.RealIP(audit.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, ok = audit.FromContext(r.Context())
+	}))))
+
+	req := httptest.NewRequest("DELETE", "/delete-banner/1", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if !ok {
+		t.Fatal("expected request data in context")
+	}
+	if request.IP != "203.0.113.7" {
+		t.Errorf("expected IP 203.0.113.7, got %q", request.IP)
+	}
+	if request.ID == "" || w.Header().Get(middleware.RequestIDHeader) != request.ID {
+		t.Errorf("expected request ID %q in response header, got %q", request.ID, w.Header().Get(middleware.RequestIDHeader))
+	}
+}
+
+// auditStorage запоминает фильтр последнего запроса к журналу
+type auditStorage struct {
+	filter audit.Filter
+}
+
+func (s *auditStorage) GetEntries(_ context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	s.filter = filter
+	return nil, nil
+}
+
+func TestGetAuditEntriesFilter(t *testing.T) {
+	storage := &auditStorage{}
+	auditHandler := audit.NewHandler(storage, logging.GetLogger())
+
+	req := httptest.NewRequest("GET", "/audit?actor_id=3&entity=banner&from=2024-04-01&to=2024-04-15T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	auditHandler.GetEntries(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	filter := storage.filter
+	if filter.ActorID == nil || *filter.ActorID != 3 || filter.Entity != audit.EntityBanner || filter.From == nil || filter.To == nil {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+
+	req = httptest.NewRequest("GET", "/audit?from=2024-04-15&to=2024-04-01", nil)
+	w = httptest.NewRecorder()
+	auditHandler.GetEntries(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for reversed period; got %d", http.StatusBadRequest, w.Code)
+	}
+}
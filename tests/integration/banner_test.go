@@ -8,6 +8,7 @@ import (
 	"banner-service/pkg/logging"
 	"context"
 	"encoding/json"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -17,6 +18,24 @@ import (
 
 var configPath = "../../configureTestDB.yml"
 
+// openTestDB подключается к тестовой базе данных и пропускает тест, если она недоступна
+func openTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	logger := logging.GetLogger()
+	cfg := config.GetConfig(logger, configPath)
+
+	testDB, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = testDB.Ping(context.TODO()); err != nil {
+		testDB.Close()
+		t.Skipf("test database is unavailable: %v", err)
+	}
+	t.Cleanup(testDB.Close)
+	return testDB
+}
+
 type fakeAdminChecker struct{}
 
 func (f *fakeAdminChecker) CheckIfAdmin(req *http.Request) (bool, error) {
@@ -0,0 +1,40 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetUserBannersServesCachedPairs(t *testing.T) {
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	// Ключ кэша: тег, фича и язык; без настроенных языков язык пустой
+	cache.SetBanners(context.TODO(), "2-1-", []*banner.Banner{{ID: 7, Title: "Banner 7", IsActive: true}})
+
+	// Репозиторий не нужен: все пары запроса есть в кэше
+	bannerHandler := banner.NewHandler(nil, logging.GetLogger(), cache)
+
+	body := strings.NewReader(`[{"feature_id": 1, "tag_id": 2}, {"feature_id": 1, "tag_id": 2}]`)
+	req := httptest.NewRequest("POST", "/user_banners", body)
+	w := httptest.NewRecorder()
+	bannerHandler.GetUserBanners(w, req, &fakeAdminChecker{})
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var results map[string]banner.UserBannerResult
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	result, ok := results["1:2"]
+	if !ok || result.Status != http.StatusOK || result.Banner == nil || result.Banner.ID != 7 {
+		t.Errorf("expected banner 7 for pair 1:2, got %+v", results)
+	}
+}
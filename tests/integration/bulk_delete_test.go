@@ -0,0 +1,297 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/job"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryJobs хранит задачи в памяти и, как база данных, повторно выдает задачи с истекшим lease
+type memoryJobs struct {
+	mutex       sync.Mutex
+	jobs        []*job.Job
+	lockedUntil map[int]time.Time
+	// progress - значения processed, сохраненные задачами
+	progress []int
+}
+
+func (m *memoryJobs) Create(_ context.Context, j *job.Job) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	j.ID = len(m.jobs) + 1
+	j.Status = job.StatusPending
+	j.CreatedAt = time.Now()
+	j.UpdatedAt = j.CreatedAt
+	stored := *j
+	m.jobs = append(m.jobs, &stored)
+	return nil
+}
+
+func (m *memoryJobs) FindOne(_ context.Context, id int) (*job.Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if id < 1 || id > len(m.jobs) {
+		return nil, job.ErrJobNotFound
+	}
+	found := *m.jobs[id-1]
+	return &found, nil
+}
+
+func (m *memoryJobs) Claim(_ context.Context, lease time.Duration) (*job.Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	for _, j := range m.jobs {
+		if j.Status == job.StatusPending || (j.Status == job.StatusRunning && m.lockedUntil[j.ID].Before(now)) {
+			j.Status = job.StatusRunning
+			m.lockedUntil[j.ID] = now.Add(lease)
+			claimed := *j
+			return &claimed, nil
+		}
+	}
+	return nil, job.ErrNoPendingJobs
+}
+
+func (m *memoryJobs) UpdateProgress(_ context.Context, id, processed int, lease time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.jobs[id-1].Processed = processed
+	m.lockedUntil[id] = time.Now().Add(lease)
+	m.progress = append(m.progress, processed)
+	return nil
+}
+
+func (m *memoryJobs) Finish(_ context.Context, id int, status string, errMessage *string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	m.jobs[id-1].Status = status
+	m.jobs[id-1].Error = errMessage
+	m.jobs[id-1].FinishedAt = &now
+	delete(m.lockedUntil, id)
+	return nil
+}
+
+// bulkStorage удаляет баннеры пачками. Если задан gate, каждая пачка ждет разрешения из него.
+type bulkStorage struct {
+	banner.Storage
+	mutex   sync.Mutex
+	banners map[int]*banner.Banner
+	gate    chan struct{}
+}
+
+func (s *bulkStorage) DeleteBannersBatch(ctx context.Context, featureID, tagID *int, limit int) ([]int, error) {
+	if s.gate != nil {
+		select {
+		case <-s.gate:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := make([]int, 0, len(s.banners))
+	for id := range s.banners {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var deleted []int
+	for _, id := range ids {
+		bn := s.banners[id]
+		if len(deleted) == limit {
+			break
+		}
+		if (featureID == nil || bn.FeatureID.ID == *featureID) && (tagID == nil || hasTagID(bn.Tags, *tagID)) {
+			delete(s.banners, id)
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+func (s *bulkStorage) remaining() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.banners)
+}
+
+func hasTagID(tags []banner.Tag, id int) bool {
+	for _, tag := range tags {
+		if tag.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// newBulkBanners создает баннеры с идентификаторами from..to на паре (feature, 1)
+func newBulkBanners(banners map[int]*banner.Banner, from, to, feature int) map[int]*banner.Banner {
+	for id := from; id <= to; id++ {
+		banners[id] = &banner.Banner{ID: id, FeatureID: banner.Feature{ID: feature}, Tags: []banner.Tag{{ID: 1}}}
+	}
+	return banners
+}
+
+// waitJob ждет, пока задача id не перейдет в статус status
+func waitJob(t *testing.T, jobs *memoryJobs, id int, status string) *job.Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		found, err := jobs.FindOne(context.TODO(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status == status {
+			return found
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d is still %s, expected %s", id, found.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBulkDeleteBanners(t *testing.T) {
+	storage := &bulkStorage{banners: newBulkBanners(newBulkBanners(map[int]*banner.Banner{}, 1, 250, 1), 251, 260, 2), gate: make(chan struct{})}
+	jobs := &memoryJobs{lockedUntil: map[int]time.Time{}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	logger := logging.GetLogger()
+
+	bannerHandler := banner.NewHandler(storage, logger, cache)
+	bannerHandler.Jobs = jobs
+	router := chi.NewRouter()
+	router.Delete("/banner", bannerHandler.BulkDeleteBanners)
+	router.Get("/jobs/{id}", job.NewHandler(jobs, logger).GetJob)
+	getJob := func(id int) job.Job {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/jobs/%d", id), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var found job.Job
+		if err := json.Unmarshal(w.Body.Bytes(), &found); err != nil {
+			t.Fatal(err)
+		}
+		return found
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/banner", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without filters; got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/banner?feature_id=1", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	var accepted struct {
+		JobID  int    `json:"job_id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil {
+		t.Fatal(err)
+	}
+	if accepted.JobID == 0 || accepted.Status != job.StatusPending || w.Header().Get("Location") != fmt.Sprintf("/jobs/%d", accepted.JobID) {
+		t.Fatalf("unexpected response %+v with Location %q", accepted, w.Header().Get("Location"))
+	}
+	if found := getJob(accepted.JobID); found.Status != job.StatusPending || found.Type != banner.JobTypeDeleteBanners {
+		t.Errorf("expected pending bulk delete job, got %+v", found)
+	}
+
+	// Удаленные баннеры убираются из кэша, остальные остаются
+	cache.SetBanners(context.TODO(), "1-1-ru", []*banner.Banner{storage.banners[1]})
+	cache.SetBanners(context.TODO(), "1-2-ru", []*banner.Banner{storage.banners[251]})
+
+	runner := job.NewRunner(jobs, logger, 10*time.Millisecond)
+	runner.Register(banner.JobTypeDeleteBanners, banner.NewBulkDeleteTask(storage, cache, logger))
+	runner.Start()
+	defer runner.Stop()
+
+	// Прогресс виден, пока задача выполняется
+	storage.gate <- struct{}{}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		found := getJob(accepted.JobID)
+		if found.Processed == 100 {
+			if found.Status != job.StatusRunning {
+				t.Errorf("expected running job after first batch, got %+v", found)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected progress of first batch, got %+v", found)
+		}
+	}
+	close(storage.gate)
+
+	waitJob(t, jobs, accepted.JobID, job.StatusDone)
+	if found := getJob(accepted.JobID); found.Processed != 250 || found.FinishedAt == nil || found.Error != nil {
+		t.Errorf("expected finished job with 250 banners processed, got %+v", found)
+	}
+	jobs.mutex.Lock()
+	if !reflect.DeepEqual(jobs.progress, []int{100, 200, 250}) {
+		t.Errorf("expected progress saved after each batch of 100, got %v", jobs.progress)
+	}
+	jobs.mutex.Unlock()
+	if remaining := storage.remaining(); remaining != 10 {
+		t.Errorf("expected banners of other features to remain, got %d banners", remaining)
+	}
+	if _, ok := cache.GetBanners(context.TODO(), "1-1-ru"); ok {
+		t.Error("expected deleted banners to be evicted from cache")
+	}
+	if _, ok := cache.GetBanners(context.TODO(), "1-2-ru"); !ok {
+		t.Error("expected other banners to stay in cache")
+	}
+}
+
+func TestBulkDeleteResumesAfterRestart(t *testing.T) {
+	// Первые 100 баннеров удалены до перезапуска, пачка 101..150 осталась
+	storage := &bulkStorage{banners: newBulkBanners(map[int]*banner.Banner{}, 101, 150, 1)}
+	params := json.RawMessage(`{"feature_id": 1}`)
+	jobs := &memoryJobs{
+		jobs: []*job.Job{
+			{ID: 1, Type: banner.JobTypeDeleteBanners, Params: params, Status: job.StatusRunning, Processed: 100},
+			{ID: 2, Type: banner.JobTypeDeleteBanners, Params: params, Status: job.StatusRunning},
+		},
+		lockedUntil: map[int]time.Time{
+			// lease задачи 1 истек вместе с упавшим сервером, задачу 2 еще выполняет другой экземпляр
+			1: time.Now().Add(-time.Second),
+			2: time.Now().Add(time.Hour),
+		},
+	}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	logger := logging.GetLogger()
+
+	runner := job.NewRunner(jobs, logger, 10*time.Millisecond)
+	runner.Register(banner.JobTypeDeleteBanners, banner.NewBulkDeleteTask(storage, cache, logger))
+	runner.Start()
+	defer runner.Stop()
+
+	resumed := waitJob(t, jobs, 1, job.StatusDone)
+	if resumed.Processed != 150 {
+		t.Errorf("expected resumed job to continue from 100 to 150, got %d", resumed.Processed)
+	}
+	if remaining := storage.remaining(); remaining != 0 {
+		t.Errorf("expected remaining banners to be deleted, got %d", remaining)
+	}
+	if leased, _ := jobs.FindOne(context.TODO(), 2); leased.Status != job.StatusRunning || leased.Processed != 0 {
+		t.Errorf("expected job with active lease to be left alone, got %+v", leased)
+	}
+}
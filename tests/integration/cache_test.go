@@ -0,0 +1,50 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCacheRespectsScheduleWindow(t *testing.T) {
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+
+	// Баннер, окно показа которого закончится раньше TTL кэша
+	endsAt := time.Now().Add(50 * time.Millisecond)
+	cache.SetBanners(context.TODO(), "1-1", []*banner.Banner{{ID: 1, IsActive: true, EndsAt: &endsAt}})
+
+	if _, ok := cache.GetBanners(context.TODO(), "1-1"); !ok {
+		t.Fatalf("expected banner to be cached before ends_at")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, ok := cache.GetBanners(context.TODO(), "1-1"); ok {
+		t.Errorf("expected banner to be evicted after ends_at")
+	}
+}
+
+func TestUserBannerCacheExpiresUnderPolling(t *testing.T) {
+	storage := &candidateStorage{candidates: []*banner.Banner{{ID: 1, Title: "Banner", IsActive: true, Version: 1}}}
+	cache := banner.NewBannerCache(100 * time.Millisecond)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	// Попадания в кэш не продлевают запись: при постоянном опросе она все равно истекает по TTL
+	for deadline := time.Now().Add(250 * time.Millisecond); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		w := httptest.NewRecorder()
+		bannerHandler.GetUserBanner(w, httptest.NewRequest("GET", "/user_banner?tag_id=1&feature_id=1", nil), &fakeAdminChecker{})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	if storage.calls < 2 || storage.calls > 4 {
+		t.Errorf("expected candidates reloaded once per TTL, got %d loads", storage.calls)
+	}
+}
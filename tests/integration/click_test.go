@@ -0,0 +1,174 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/user"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clickStorage отдает один баннер для любой пары (фича, тег) и его URL для перехода
+type clickStorage struct {
+	banner.Storage
+	banner *banner.Banner
+}
+
+func (s *clickStorage) GetBannerCandidates(_ context.Context, _, _ int, _ bool) ([]*banner.Banner, error) {
+	return []*banner.Banner{s.banner}, nil
+}
+
+func (s *clickStorage) GetBannerURL(_ context.Context, id int, _ *int) (string, error) {
+	if id != s.banner.ID {
+		return "", banner.ErrBannerNotFound
+	}
+	return s.banner.URL, nil
+}
+
+func newClickHandler(t *testing.T, links *banner.LinkSigner) (*banner.Handler, *memoryImpressions) {
+	storage := &clickStorage{banner: &banner.Banner{ID: 1, Title: "Banner", Text: "Text", URL: "https://example.com/promo", IsActive: true, Version: 1}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	t.Cleanup(cache.Close)
+	clicks := &memoryImpressions{}
+
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+	bannerHandler.Links = links
+	bannerHandler.Clicks = clicks
+	return bannerHandler, clicks
+}
+
+func withUser(req *http.Request, userID int) *http.Request {
+	return req.WithContext(user.NewContext(req.Context(), &user.CustomClaims{ID: userID}))
+}
+
+// trackedURL запрашивает баннер с tracking=true от имени пользователя userID (0 - без токена)
+func trackedURL(t *testing.T, bannerHandler *banner.Handler, userID int) string {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/user_banner?tag_id=1&feature_id=1&tracking=true", nil)
+	req.Host = "attacker.example.net"
+	if userID != 0 {
+		req = withUser(req, userID)
+	}
+	w := httptest.NewRecorder()
+	bannerHandler.GetUserBanner(w, req, &fakeAdminChecker{})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response banner.Banner
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.URL
+}
+
+func click(bannerHandler *banner.Handler, target string, userID int) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Get("/banner/{id}/click", bannerHandler.ClickBanner)
+
+	req := httptest.NewRequest("GET", target, nil)
+	if userID != 0 {
+		req = withUser(req, userID)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestClickLink(t *testing.T) {
+	bannerHandler, clicks := newClickHandler(t, banner.NewLinkSigner("secret", "https://banners.example.com/", time.Hour))
+
+	link := trackedURL(t, bannerHandler, 5)
+	// Адрес ссылки берется из конфигурации, а не из заголовка Host запроса
+	if !strings.HasPrefix(link, "https://banners.example.com/banner/1/click?") {
+		t.Fatalf("unexpected click URL %s", link)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Query().Get("uid") != "5" || parsed.Query().Get("exp") == "" {
+		t.Errorf("expected uid and exp in click URL %s", link)
+	}
+
+	w := click(bannerHandler, parsed.RequestURI(), 5)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusFound, w.Code, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); location != "https://example.com/promo" {
+		t.Errorf("expected redirect to banner URL, got %s", location)
+	}
+	if len(clicks.clicks) != 1 || clicks.clicks[0].UserID == nil || *clicks.clicks[0].UserID != 5 {
+		t.Errorf("expected click of user 5, got %+v", clicks.clicks)
+	}
+
+	tamper := func(key, value string) string {
+		query := parsed.Query()
+		if value == "" {
+			query.Del(key)
+		} else {
+			query.Set(key, value)
+		}
+		return parsed.Path + "?" + query.Encode()
+	}
+	exp := parsed.Query().Get("exp")
+	tests := []struct {
+		name   string
+		target string
+		userID int
+	}{
+		{name: "another user", target: parsed.RequestURI(), userID: 6},
+		{name: "anonymous user", target: parsed.RequestURI()},
+		{name: "changed uid", target: tamper("uid", "6"), userID: 6},
+		{name: "removed uid", target: tamper("uid", ""), userID: 5},
+		{name: "extended expiry", target: tamper("exp", exp+"0"), userID: 5},
+		{name: "changed tag", target: tamper("tag_id", "2"), userID: 5},
+		{name: "missing signature", target: tamper("sig", ""), userID: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := click(bannerHandler, tt.target, tt.userID)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected status %d; got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+			}
+		})
+	}
+	if len(clicks.clicks) != 1 {
+		t.Errorf("rejected links must not be recorded, got %d clicks", len(clicks.clicks))
+	}
+}
+
+func TestClickLinkAnonymousAndRelative(t *testing.T) {
+	bannerHandler, clicks := newClickHandler(t, banner.NewLinkSigner("secret", "", time.Hour))
+
+	link := trackedURL(t, bannerHandler, 0)
+	if !strings.HasPrefix(link, "/banner/1/click?") || strings.Contains(link, "uid=") {
+		t.Fatalf("expected relative anonymous click URL, got %s", link)
+	}
+
+	w := click(bannerHandler, link, 0)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusFound, w.Code, w.Body.String())
+	}
+	if len(clicks.clicks) != 1 || clicks.clicks[0].UserID != nil {
+		t.Errorf("expected anonymous click, got %+v", clicks.clicks)
+	}
+}
+
+func TestClickLinkExpired(t *testing.T) {
+	bannerHandler, clicks := newClickHandler(t, banner.NewLinkSigner("secret", "", -time.Minute))
+
+	w := click(bannerHandler, trackedURL(t, bannerHandler, 5), 5)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d; got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+	if len(clicks.clicks) != 0 {
+		t.Errorf("expired link must not be recorded, got %+v", clicks.clicks)
+	}
+}
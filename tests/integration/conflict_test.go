@@ -0,0 +1,116 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// conflictStorage отвечает на любое сохранение баннера ошибкой err, по умолчанию - конфликтом пары (фича, тег)
+type conflictStorage struct {
+	banner.Storage
+	bannerIDs []int
+	err       error
+}
+
+func (s *conflictStorage) saveError() error {
+	if s.err != nil {
+		return s.err
+	}
+	return &banner.ConflictError{BannerIDs: s.bannerIDs}
+}
+
+func (s *conflictStorage) GetFeatureSchema(_ context.Context, _ int) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (s *conflictStorage) CreateBanner(_ context.Context, _ *banner.Banner) error {
+	return s.saveError()
+}
+
+func (s *conflictStorage) UpdateBanner(_ context.Context, _ *banner.Banner) error {
+	return s.saveError()
+}
+
+func TestSaveBannerConflict(t *testing.T) {
+	storage := &conflictStorage{bannerIDs: []int{3, 5}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	router := chi.NewRouter()
+	router.Post("/banner", bannerHandler.CreateBannerHandler)
+	router.Put("/banner/{id}", bannerHandler.UpdateBannerHandler)
+
+	body := `{"title": "Banner", "text": "Text", "url": "https://example.com", "feature_id": {"id": 1}, "tags": [{"id": 1}, {"id": 2}]}`
+	for _, target := range []struct{ method, path string }{{"POST", "/banner"}, {"PUT", "/banner/7"}} {
+		req := httptest.NewRequest(target.method, target.path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s %s: expected status %d; got %d: %s", target.method, target.path, http.StatusConflict, w.Code, w.Body.String())
+			continue
+		}
+		var response struct {
+			Message   string `json:"message"`
+			BannerIDs []int  `json:"banner_ids"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(response.BannerIDs, storage.bannerIDs) || response.Message == "" {
+			t.Errorf("%s %s: expected conflicting banners %v, got %+v", target.method, target.path, storage.bannerIDs, response)
+		}
+	}
+}
+
+func TestSaveBannerErrors(t *testing.T) {
+	internal := errors.New("pq: connection to 10.0.0.5 reset")
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "not found", err: banner.ErrBannerNotFound, status: http.StatusNotFound},
+		{name: "modified", err: banner.ErrPreconditionFailed, status: http.StatusPreconditionFailed},
+		{name: "timeout", err: fmt.Errorf("save banner: %w", context.DeadlineExceeded), status: http.StatusRequestTimeout},
+		{name: "internal", err: internal, status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &conflictStorage{err: tt.err}
+			cache := banner.NewBannerCache(5 * time.Minute)
+			defer cache.Close()
+			bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+			router := chi.NewRouter()
+			router.Post("/banner", bannerHandler.CreateBannerHandler)
+			router.Put("/banner/{id}", bannerHandler.UpdateBannerHandler)
+
+			body := `{"title": "Banner", "text": "Text", "url": "https://example.com", "feature_id": {"id": 1}, "tags": [{"id": 1}]}`
+			for _, target := range []struct{ method, path string }{{"POST", "/banner"}, {"PUT", "/banner/7"}} {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(target.method, target.path, strings.NewReader(body)))
+
+				if w.Code != tt.status {
+					t.Errorf("%s %s: expected status %d; got %d: %s", target.method, target.path, tt.status, w.Code, w.Body.String())
+				}
+				// Текст внутренних ошибок не отдается клиенту
+				if strings.Contains(w.Body.String(), internal.Error()) {
+					t.Errorf("%s %s: internal error exposed in response %q", target.method, target.path, w.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schemaStorage отдает JSON Schema фичи и принимает любой баннер
+type schemaStorage struct {
+	banner.Storage
+	schema json.RawMessage
+}
+
+func (s *schemaStorage) GetFeatureSchema(_ context.Context, _ int) (json.RawMessage, error) {
+	return s.schema, nil
+}
+
+func (s *schemaStorage) CreateBanner(_ context.Context, bn *banner.Banner) error {
+	bn.ID, bn.Version = 1, 1
+	return nil
+}
+
+func TestCreateBannerContentValidation(t *testing.T) {
+	storage := &schemaStorage{schema: json.RawMessage(`{
+		"type": "object",
+		"required": ["cta"],
+		"properties": {
+			"cta": {
+				"type": "object",
+				"required": ["url"],
+				"properties": {"url": {"type": "string"}, "labels": {"type": "array", "items": {"type": "string"}}}
+			}
+		}
+	}`)}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	tests := []struct {
+		name    string
+		content string
+		status  int
+		fields  []string
+	}{
+		{name: "valid", content: `{"cta": {"url": "https://example.com", "labels": ["a"]}}`, status: http.StatusCreated},
+		{name: "missing content", content: `null`, status: http.StatusBadRequest, fields: []string{"content"}},
+		{name: "missing nested property", content: `{"cta": {}}`, status: http.StatusBadRequest, fields: []string{"content.cta"}},
+		{name: "nested type", content: `{"cta": {"url": 5}}`, status: http.StatusBadRequest, fields: []string{"content.cta.url"}},
+		{name: "array item", content: `{"cta": {"url": "u", "labels": ["a", 1]}}`, status: http.StatusBadRequest, fields: []string{"content.cta.labels.1"}},
+	}
+
+	// Повторная проверка по той же схеме берет скомпилированную схему из кэша
+	for i := 0; i < 2; i++ {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				body := `{"title": "Banner", "text": "Text", "url": "https://example.com", "feature_id": {"id": 1}, "tags": [{"id": 1}], "content": ` + tt.content + `}`
+				req := httptest.NewRequest("POST", "/banner", strings.NewReader(body))
+				w := httptest.NewRecorder()
+				bannerHandler.CreateBannerHandler(w, req)
+
+				if w.Code != tt.status {
+					t.Fatalf("expected status %d; got %d: %s", tt.status, w.Code, w.Body.String())
+				}
+				if tt.fields == nil {
+					return
+				}
+				var validationErr banner.ValidationError
+				if err := json.Unmarshal(w.Body.Bytes(), &validationErr); err != nil {
+					t.Fatal(err)
+				}
+				var fields []string
+				for _, field := range validationErr.Fields {
+					fields = append(fields, field.Field)
+				}
+				if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+					t.Errorf("expected errors in %v, got %+v", tt.fields, validationErr.Fields)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// pageStorage отдает страницу из одного баннера и запоминает фильтр последнего запроса
+type pageStorage struct {
+	banner.Storage
+	banner *banner.Banner
+	filter *banner.Filter
+}
+
+func (s *pageStorage) GetBannersByFiltering(_ context.Context, filter banner.Filter) (*banner.Page, error) {
+	s.filter = &filter
+	return &banner.Page{
+		Banners:    []*banner.Banner{s.banner},
+		Total:      2,
+		NextCursor: banner.NewCursor(s.banner, filter),
+	}, nil
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 4, 1, 9, 0, 0, 123456789, time.UTC)
+	bn := &banner.Banner{ID: 7, Title: "Banner", CreatedAt: createdAt, UpdatedAt: createdAt}
+
+	for _, sort := range []string{"id", "-created_at", "updated_at", "-title"} {
+		field, desc, err := banner.ParseSort(sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cursor := banner.NewCursor(bn, banner.Filter{Sort: field, Desc: desc})
+
+		decoded, err := banner.DecodeCursor(cursor.Encode(), field, desc)
+		if err != nil {
+			t.Fatalf("%s: %v", sort, err)
+		}
+		if !reflect.DeepEqual(decoded, cursor) {
+			t.Errorf("%s: expected cursor %+v, got %+v", sort, cursor, decoded)
+		}
+	}
+}
+
+func TestGetBannersCursor(t *testing.T) {
+	createdAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	storage := &pageStorage{banner: &banner.Banner{ID: 7, Title: "Banner", CreatedAt: createdAt}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	getBanners := func(query url.Values) *httptest.ResponseRecorder {
+		storage.filter = nil
+		w := httptest.NewRecorder()
+		bannerHandler.GetBanners(w, httptest.NewRequest("GET", "/banner?"+query.Encode(), nil))
+		return w
+	}
+
+	w := getBanners(url.Values{"sort": {"-created_at"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.NextCursor == "" {
+		t.Fatal("expected next_cursor in response")
+	}
+
+	// Курсор из ответа передается в хранилище как позиция следующей страницы
+	w = getBanners(url.Values{"sort": {"-created_at"}, "cursor": {response.NextCursor}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	expected := &banner.Cursor{Sort: "-created_at", Value: createdAt.Format(time.RFC3339Nano), ID: 7}
+	if storage.filter == nil || !reflect.DeepEqual(storage.filter.After, expected) {
+		t.Errorf("expected cursor %+v passed to storage, got %+v", expected, storage.filter)
+	}
+
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"-created_at","v":"yesterday","id":7}`))
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{name: "garbage", query: url.Values{"sort": {"-created_at"}, "cursor": {"not a cursor!"}}},
+		{name: "not json", query: url.Values{"sort": {"-created_at"}, "cursor": {base64.RawURLEncoding.EncodeToString([]byte("7"))}}},
+		{name: "tampered value", query: url.Values{"sort": {"-created_at"}, "cursor": {tampered}}},
+		{name: "other sort", query: url.Values{"sort": {"title"}, "cursor": {response.NextCursor}}},
+		{name: "other direction", query: url.Values{"sort": {"created_at"}, "cursor": {response.NextCursor}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getBanners(tt.query)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d; got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+			if storage.filter != nil {
+				t.Error("invalid cursor must not reach storage")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// dictionaryStorage имитирует тег и фичу, на которые ссылается баннер bannerID
+type dictionaryStorage struct {
+	banner.TagStorage
+	banner.FeatureStorage
+	bannerID int
+}
+
+func (s *dictionaryStorage) delete(cascade bool) ([]int, error) {
+	if !cascade {
+		return nil, &banner.InUseError{BannerIDs: []int{s.bannerID}}
+	}
+	return []int{s.bannerID}, nil
+}
+
+func (s *dictionaryStorage) DeleteTag(_ context.Context, _ int, cascade bool) ([]int, error) {
+	return s.delete(cascade)
+}
+
+func (s *dictionaryStorage) DeleteFeature(_ context.Context, _ int, cascade bool) ([]int, error) {
+	return s.delete(cascade)
+}
+
+func TestDeleteTagAndFeatureInUse(t *testing.T) {
+	storage := &dictionaryStorage{bannerID: 7}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+
+	tagHandler := banner.NewTagHandler(storage, logging.GetLogger())
+	tagHandler.Cache = cache
+	featureHandler := banner.NewFeatureHandler(storage, logging.GetLogger())
+	featureHandler.Cache = cache
+
+	router := chi.NewRouter()
+	router.Delete("/tags/{id}", tagHandler.DeleteTag)
+	router.Delete("/features/{id}", featureHandler.DeleteFeature)
+
+	for _, path := range []string{"/tags/1", "/features/1"} {
+		cache.SetBanners(context.TODO(), "1-1-", []*banner.Banner{{ID: 7, IsActive: true}})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", path, nil))
+		if w.Code != http.StatusConflict {
+			t.Fatalf("%s: expected status %d; got %d: %s", path, http.StatusConflict, w.Code, w.Body.String())
+		}
+		var response struct {
+			BannerIDs []int `json:"banner_ids"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(response.BannerIDs, []int{7}) {
+			t.Errorf("%s: expected banner_ids [7], got %v", path, response.BannerIDs)
+		}
+		if _, ok := cache.GetBanners(context.TODO(), "1-1-"); !ok {
+			t.Errorf("%s: refused deletion must not touch the cache", path)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", path+"?cascade=true", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d for cascade; got %d: %s", path, http.StatusOK, w.Code, w.Body.String())
+		}
+		if _, ok := cache.GetBanners(context.TODO(), "1-1-"); ok {
+			t.Errorf("%s: expected banner deleted by cascade to be evicted from cache", path)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// candidateStorage отдает один и тот же набор кандидатов для любой пары (фича, тег)
+type candidateStorage struct {
+	banner.Storage
+	candidates []*banner.Banner
+	// calls - число обращений к хранилищу, то есть промахов кэша
+	calls int
+}
+
+func (s *candidateStorage) GetBannerCandidates(_ context.Context, _, _ int, _ bool) ([]*banner.Banner, error) {
+	s.calls++
+	return s.candidates, nil
+}
+
+func TestGetUserBannerConditional(t *testing.T) {
+	modified := time.Date(2024, 4, 14, 17, 22, 24, 0, time.UTC)
+	storage := &candidateStorage{candidates: []*banner.Banner{{
+		ID: 1, Title: "Banner", Text: "Text", URL: "https://example.com", IsActive: true, Version: 2, UpdatedAt: modified,
+	}}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	const etag = `"1-2"`
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+	tests := []struct {
+		name            string
+		ifNoneMatch     string
+		ifModifiedSince string
+		status          int
+	}{
+		{name: "no validators", status: http.StatusOK},
+		{name: "matching etag", ifNoneMatch: etag, status: http.StatusNotModified},
+		{name: "weak etag in list", ifNoneMatch: `"1-1", W/"1-2"`, status: http.StatusNotModified},
+		{name: "any etag", ifNoneMatch: "*", status: http.StatusNotModified},
+		{name: "stale etag", ifNoneMatch: `"1-1"`, status: http.StatusOK},
+		{name: "not modified since", ifModifiedSince: modified.Format(http.TimeFormat), status: http.StatusNotModified},
+		{name: "modified since", ifModifiedSince: before, status: http.StatusOK},
+		{name: "invalid date", ifModifiedSince: "yesterday", status: http.StatusOK},
+		// If-Modified-Since игнорируется, если передан If-None-Match
+		{name: "stale etag wins over date", ifNoneMatch: `"1-1"`, ifModifiedSince: after, status: http.StatusOK},
+		{name: "matching etag wins over date", ifNoneMatch: etag, ifModifiedSince: before, status: http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/user_banner?tag_id=1&feature_id=1", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			if tt.ifModifiedSince != "" {
+				req.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+			}
+			w := httptest.NewRecorder()
+			bannerHandler.GetUserBanner(w, req, &fakeAdminChecker{})
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d; got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("expected ETag %s, got %q", etag, w.Header().Get("ETag"))
+			}
+			if w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+				t.Errorf("expected Last-Modified %s, got %q", modified.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+			}
+			if tt.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("expected empty body for 304, got %q", w.Body.String())
+			}
+			if tt.status == http.StatusOK && w.Body.Len() == 0 {
+				t.Error("expected banner in response body")
+			}
+		})
+	}
+}
+
+func TestGetUserBannerConditionalTracking(t *testing.T) {
+	get := func(bannerHandler *banner.Handler, query string, userID int, header, value string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/user_banner?tag_id=1&feature_id=1"+query, nil)
+		if userID != 0 {
+			req = withUser(req, userID)
+		}
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		bannerHandler.GetUserBanner(w, req, &fakeAdminChecker{})
+		return w
+	}
+
+	bannerHandler, _ := newClickHandler(t, banner.NewLinkSigner("secret", "", time.Hour))
+	plain := get(bannerHandler, "", 5, "", "").Header().Get("ETag")
+	tracked := get(bannerHandler, "&tracking=true", 5, "", "")
+	etag := tracked.Header().Get("ETag")
+	if etag == "" || etag == plain {
+		t.Fatalf("expected tracking response to have its own ETag, got %q and %q", etag, plain)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		userID int
+		header string
+		value  string
+		status int
+	}{
+		{name: "same link", query: "&tracking=true", userID: 5, header: "If-None-Match", value: etag, status: http.StatusNotModified},
+		{name: "copy without link", query: "&tracking=true", userID: 5, header: "If-None-Match", value: plain, status: http.StatusOK},
+		{name: "link of another user", query: "&tracking=true", userID: 6, header: "If-None-Match", value: etag, status: http.StatusOK},
+		{name: "copy with link", userID: 5, header: "If-None-Match", value: etag, status: http.StatusOK},
+		{name: "not modified since", query: "&tracking=true", userID: 5, header: "If-Modified-Since", value: tracked.Header().Get("Last-Modified"), status: http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := get(bannerHandler, tt.query, tt.userID, tt.header, tt.value); w.Code != tt.status {
+				t.Errorf("expected status %d; got %d", tt.status, w.Code)
+			}
+		})
+	}
+
+	// Ссылки выдаются на период в половину ttl: в следующем периоде копия с прежней ссылкой устаревает
+	bannerHandler, _ = newClickHandler(t, banner.NewLinkSigner("secret", "", 2*time.Second))
+	tracked = get(bannerHandler, "&tracking=true", 5, "", "")
+	time.Sleep(1100 * time.Millisecond)
+	if w := get(bannerHandler, "&tracking=true", 5, "If-None-Match", tracked.Header().Get("ETag")); w.Code != http.StatusOK {
+		t.Errorf("expected status %d for expiring link; got %d", http.StatusOK, w.Code)
+	}
+	if w := get(bannerHandler, "&tracking=true", 5, "If-Modified-Since", tracked.Header().Get("Last-Modified")); w.Code != http.StatusOK {
+		t.Errorf("expected status %d for expiring link by date; got %d", http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,162 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/banner/dbbanner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestGetBannersFilterParameters(t *testing.T) {
+	published := banner.StatusPublished
+	tests := []struct {
+		name   string
+		query  string
+		filter banner.Filter
+	}{
+		{name: "defaults", query: "", filter: banner.Filter{Limit: 20, Sort: "id"}},
+		{name: "feature only", query: "feature_id=2", filter: banner.Filter{FeatureID: intPtr(2), Limit: 20, Sort: "id"}},
+		{name: "tag only", query: "tag_id=3", filter: banner.Filter{TagID: intPtr(3), Limit: 20, Sort: "id"}},
+		{
+			name:   "all filters",
+			query:  "feature_id=1&tag_id=2&status=published&limit=5&offset=10",
+			filter: banner.Filter{FeatureID: intPtr(1), TagID: intPtr(2), Status: &published, Limit: 5, Offset: 10, Sort: "id"},
+		},
+		{name: "limit above maximum", query: "limit=1000", filter: banner.Filter{Limit: 100, Sort: "id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &pageStorage{banner: &banner.Banner{ID: 7, Title: "Banner"}}
+			bannerHandler := banner.NewHandler(storage, logging.GetLogger(), nil)
+
+			w := httptest.NewRecorder()
+			bannerHandler.GetBanners(w, httptest.NewRequest("GET", "/banner?"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if !reflect.DeepEqual(*storage.filter, tt.filter) {
+				t.Errorf("expected filter %+v, got %+v", tt.filter, *storage.filter)
+			}
+			if total := w.Header().Get("X-Total-Count"); total != "2" {
+				t.Errorf("expected X-Total-Count 2, got %q", total)
+			}
+		})
+	}
+
+	for _, query := range []string{"feature_id=a", "tag_id=1.5", "status=deleted", "limit=0", "limit=-1", "offset=-1", "offset=x"} {
+		t.Run("invalid "+query, func(t *testing.T) {
+			storage := &pageStorage{banner: &banner.Banner{ID: 7}}
+			bannerHandler := banner.NewHandler(storage, logging.GetLogger(), nil)
+
+			w := httptest.NewRecorder()
+			bannerHandler.GetBanners(w, httptest.NewRequest("GET", "/banner?"+query, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d; got %d", http.StatusBadRequest, w.Code)
+			}
+			if storage.filter != nil {
+				t.Errorf("storage must not be queried for invalid parameters")
+			}
+		})
+	}
+}
+
+func TestGetBannerFilterDeprecatedAlias(t *testing.T) {
+	storage := &pageStorage{banner: &banner.Banner{ID: 7, Title: "Banner"}}
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), nil)
+	router := chi.NewRouter()
+	router.Get("/banner/{feature_id}/{tag_id}/{limit}/{offset}", bannerHandler.GetBannerFilter)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/banner/1/2/500/3", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	expected := banner.Filter{FeatureID: intPtr(1), TagID: intPtr(2), Limit: 100, Offset: 3, Sort: "id"}
+	if !reflect.DeepEqual(*storage.filter, expected) {
+		t.Errorf("expected filter %+v, got %+v", expected, *storage.filter)
+	}
+	if w.Header().Get("Deprecation") != "true" {
+		t.Errorf("expected Deprecation header, got %q", w.Header().Get("Deprecation"))
+	}
+	if link := w.Header().Get("Link"); link != `</banner?feature_id=1&tag_id=2&limit=500&offset=3>; rel="successor-version"` {
+		t.Errorf("unexpected Link header %q", link)
+	}
+	if total := w.Header().Get("X-Total-Count"); total != "2" {
+		t.Errorf("expected X-Total-Count 2, got %q", total)
+	}
+	// Устаревший ресурс отдает массив баннеров без обертки
+	var banners []banner.Banner
+	if err := json.Unmarshal(w.Body.Bytes(), &banners); err != nil || len(banners) != 1 || banners[0].ID != 7 {
+		t.Errorf("expected plain array with banner 7, got %s", w.Body.String())
+	}
+
+	for _, target := range []string{"/banner/x/2/10/0", "/banner/1/x/10/0", "/banner/1/2/0/0", "/banner/1/2/10/-1"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d; got %d", target, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestGetBannersByFilteringDB(t *testing.T) {
+	testDB := openTestDB(t)
+	repository := dbbanner.NewBannerRepository(testDB, logging.GetLogger())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ids := func(page *banner.Page) []int {
+		result := []int{}
+		for _, bn := range page.Banners {
+			result = append(result, bn.ID)
+		}
+		return result
+	}
+
+	// Пары из тестовых данных: баннеры 1 и 2 на фиче 1, тег 1 у баннеров 1 и 6
+	tests := []struct {
+		name   string
+		filter banner.Filter
+		ids    []int
+		total  int
+	}{
+		{name: "feature", filter: banner.Filter{FeatureID: intPtr(1), Limit: 20, Sort: "id"}, ids: []int{1, 2}, total: 2},
+		{name: "tag", filter: banner.Filter{TagID: intPtr(1), Limit: 20, Sort: "id"}, ids: []int{1, 6}, total: 2},
+		{name: "feature and tag", filter: banner.Filter{FeatureID: intPtr(1), TagID: intPtr(2), Limit: 20, Sort: "id"}, ids: []int{2}, total: 1},
+		{name: "offset", filter: banner.Filter{TagID: intPtr(1), Limit: 1, Offset: 1, Sort: "id"}, ids: []int{6}, total: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repository.GetBannersByFiltering(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids(page), tt.ids) || page.Total != tt.total {
+				t.Errorf("expected banners %v of %d, got %v of %d", tt.ids, tt.total, ids(page), page.Total)
+			}
+		})
+	}
+
+	// Баннеры списка приходят с тегами и названием фичи
+	page, err := repository.GetBannersByFiltering(ctx, banner.Filter{FeatureID: intPtr(1), TagID: intPtr(1), Limit: 20, Sort: "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bn := range page.Banners {
+		if len(bn.Tags) == 0 || bn.FeatureID.Name != "Feature 1" {
+			t.Errorf("expected banner %d with tags and feature name, got %+v", bn.ID, bn)
+		}
+	}
+}
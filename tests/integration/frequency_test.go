@@ -0,0 +1,101 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/banner/dbbanner"
+	"banner-service/pkg/logging"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryFrequencyStoreCapsWithinWindow(t *testing.T) {
+	store := banner.NewMemoryFrequencyStore()
+	defer store.Close()
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := store.Allow(context.TODO(), "1:1", 3, 50*time.Millisecond); !allowed {
+			t.Fatalf("expected show %d to be allowed", i+1)
+		}
+	}
+	if allowed, _ := store.Allow(context.TODO(), "1:1", 3, 50*time.Millisecond); allowed {
+		t.Errorf("expected fourth show to be capped")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if allowed, _ := store.Allow(context.TODO(), "1:1", 3, 50*time.Millisecond); !allowed {
+		t.Errorf("expected counter to reset after window")
+	}
+}
+
+// expiringCounters сообщает о каждом вызове очистки истекших счетчиков
+type expiringCounters struct {
+	banner.FrequencyStore
+	calls chan struct{}
+}
+
+func (c *expiringCounters) DeleteExpired(_ context.Context) (int64, error) {
+	c.calls <- struct{}{}
+	return 1, nil
+}
+
+func TestFrequencyCleanerDeletesExpiredCounters(t *testing.T) {
+	counters := &expiringCounters{calls: make(chan struct{}, 10)}
+	cleaner := banner.NewFrequencyCleaner(counters, logging.GetLogger(), 10*time.Millisecond)
+	cleaner.Start()
+	defer cleaner.Stop()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-counters.calls:
+		case <-time.After(time.Second):
+			t.Fatalf("expected expired counters cleanup #%d", i+1)
+		}
+	}
+}
+
+func TestPostgresFrequencyDeleteExpired(t *testing.T) {
+	testDB := openTestDB(t)
+	store := dbbanner.NewFrequencyRepository(testDB, logging.GetLogger())
+	ctx := context.TODO()
+
+	expired := fmt.Sprintf("test-expired-%d", time.Now().UnixNano())
+	active := fmt.Sprintf("test-active-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM frequency_counters WHERE key = ANY($1)`, []string{expired, active})
+	})
+
+	for key, window := range map[string]time.Duration{expired: time.Millisecond, active: time.Hour} {
+		if allowed, err := store.Allow(ctx, key, 1, window); err != nil || !allowed {
+			t.Fatalf("expected first show of %s to be allowed, got %v, %v", key, allowed, err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	deleted, err := store.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted < 1 {
+		t.Errorf("expected expired counter to be deleted, got %d", deleted)
+	}
+
+	var keys []string
+	rows, err := testDB.Query(ctx, `SELECT key FROM frequency_counters WHERE key = ANY($1)`, []string{expired, active})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) != 1 || keys[0] != active {
+		t.Errorf("expected only %s to remain, got %v", active, keys)
+	}
+}
@@ -0,0 +1,195 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/banner/dbbanner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// versionStorage хранит версии баннеров и проверяет их так же, как репозиторий:
+// версия 0 означает запись без проверки
+type versionStorage struct {
+	banner.Storage
+	versions map[int]int
+}
+
+func (s *versionStorage) check(id, version int) error {
+	current, ok := s.versions[id]
+	if !ok {
+		return banner.ErrBannerNotFound
+	}
+	if version != 0 && version != current {
+		return banner.ErrPreconditionFailed
+	}
+	s.versions[id]++
+	return nil
+}
+
+func (s *versionStorage) GetBannerVersion(_ context.Context, id int) (int, error) {
+	current, ok := s.versions[id]
+	if !ok {
+		return 0, banner.ErrBannerNotFound
+	}
+	return current, nil
+}
+
+func (s *versionStorage) GetFeatureSchema(_ context.Context, _ int) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (s *versionStorage) UpdateBanner(_ context.Context, bn *banner.Banner) error {
+	if err := s.check(bn.ID, bn.Version); err != nil {
+		return err
+	}
+	bn.Version = s.versions[bn.ID]
+	return nil
+}
+
+func (s *versionStorage) PatchBanner(_ context.Context, id int, apply func(current *banner.Banner) error) (*banner.Banner, error) {
+	version, ok := s.versions[id]
+	if !ok {
+		return nil, banner.ErrBannerNotFound
+	}
+	current := &banner.Banner{
+		ID: id, Title: "Banner", Text: "Text", URL: "https://example.com",
+		FeatureID: banner.Feature{ID: 1}, Tags: []banner.Tag{{ID: 1}}, Version: version,
+	}
+	// apply подставляет версию из If-Match, если она передана
+	if err := apply(current); err != nil {
+		return nil, err
+	}
+	if err := s.check(id, current.Version); err != nil {
+		return nil, err
+	}
+	current.Version = s.versions[id]
+	return current, nil
+}
+
+func (s *versionStorage) DeleteBanner(_ context.Context, id, version int) error {
+	return s.check(id, version)
+}
+
+func TestIfMatch(t *testing.T) {
+	storage := &versionStorage{}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	router := chi.NewRouter()
+	router.Put("/banner/{id}", bannerHandler.UpdateBannerHandler)
+	router.Patch("/banner/{id}", bannerHandler.PatchBannerHandler)
+	router.Delete("/banner/{id}", bannerHandler.DeleteBannerHandler)
+
+	methods := []struct {
+		method string
+		body   string
+		status int
+	}{
+		{method: "PUT", body: `{"title": "Banner", "text": "Text", "url": "https://example.com", "feature_id": {"id": 1}, "tags": [{"id": 1}]}`, status: http.StatusCreated},
+		{method: "PATCH", body: `{"is_active": false}`, status: http.StatusOK},
+		{method: "DELETE", status: http.StatusOK},
+	}
+	tests := []struct {
+		name    string
+		id      int
+		ifMatch string
+		status  int
+	}{
+		{name: "missing header", id: 1},
+		{name: "any version", id: 1, ifMatch: "*"},
+		{name: "current version", id: 1, ifMatch: `"3"`},
+		{name: "stale version", id: 1, ifMatch: `"2"`, status: http.StatusPreconditionFailed},
+		{name: "weak tag", id: 1, ifMatch: `W/"3"`, status: http.StatusPreconditionFailed},
+		{name: "list with current version", id: 1, ifMatch: `"2", "3"`},
+		{name: "list with weak current version", id: 1, ifMatch: `W/"3", "4"`, status: http.StatusPreconditionFailed},
+		{name: "list with weak and strong tags", id: 1, ifMatch: `W/"2","3"`},
+		{name: "list of stale versions", id: 1, ifMatch: `"1", "2"`, status: http.StatusPreconditionFailed},
+		{name: "list with any version", id: 1, ifMatch: `"2", *`},
+		{name: "missing banner", id: 9, ifMatch: `"3"`, status: http.StatusNotFound},
+		{name: "list for missing banner", id: 9, ifMatch: `"2", "3"`, status: http.StatusNotFound},
+	}
+
+	for _, m := range methods {
+		for _, tt := range tests {
+			t.Run(m.method+" "+tt.name, func(t *testing.T) {
+				storage.versions = map[int]int{1: 3}
+
+				req := httptest.NewRequest(m.method, fmt.Sprintf("/banner/%d", tt.id), strings.NewReader(m.body))
+				if m.method == "PATCH" {
+					req.Header.Set("Content-Type", "application/merge-patch+json")
+				}
+				if tt.ifMatch != "" {
+					req.Header.Set("If-Match", tt.ifMatch)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				status := tt.status
+				if status == 0 {
+					status = m.status
+				}
+				if w.Code != status {
+					t.Fatalf("expected status %d; got %d: %s", status, w.Code, w.Body.String())
+				}
+				if tt.status == 0 && storage.versions[1] != 4 {
+					t.Errorf("expected banner version 4 after write, got %d", storage.versions[1])
+				}
+				if tt.status != 0 && storage.versions[1] != 3 {
+					t.Errorf("rejected write must not change the banner, got version %d", storage.versions[1])
+				}
+			})
+		}
+	}
+}
+
+func TestConditionalWriteMissingOrModified(t *testing.T) {
+	testDB := openTestDB(t)
+	repository := dbbanner.NewBannerRepository(testDB, logging.GetLogger())
+	ctx := context.TODO()
+
+	bn := &banner.Banner{
+		Title:     fmt.Sprintf("If-Match test %d", time.Now().UnixNano()),
+		Text:      "Text",
+		URL:       "https://example.com",
+		FeatureID: banner.Feature{ID: 5},
+		Tags:      []banner.Tag{{ID: 3}},
+		Timezone:  "UTC",
+	}
+	if err := repository.CreateBanner(ctx, bn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM banner_tags WHERE banner_id = $1`, bn.ID)
+		testDB.Exec(ctx, `DELETE FROM banners WHERE id = $1`, bn.ID)
+	})
+
+	stale := *bn
+	stale.Version = bn.Version + 1
+	if err := repository.UpdateBanner(ctx, &stale); !errors.Is(err, banner.ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed for stale update, got %v", err)
+	}
+	if err := repository.DeleteBanner(ctx, bn.ID, bn.Version+1); !errors.Is(err, banner.ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed for stale delete, got %v", err)
+	}
+	if err := repository.DeleteBanner(ctx, bn.ID, bn.Version); err != nil {
+		t.Fatal(err)
+	}
+
+	// Баннер в корзине для условной записи считается отсутствующим
+	if err := repository.DeleteBanner(ctx, bn.ID, bn.Version); !errors.Is(err, banner.ErrBannerNotFound) {
+		t.Errorf("expected ErrBannerNotFound for deleted banner, got %v", err)
+	}
+	if err := repository.UpdateBanner(ctx, bn); !errors.Is(err, banner.ErrBannerNotFound) {
+		t.Errorf("expected ErrBannerNotFound for update of deleted banner, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/impression"
+	"banner-service/pkg/logging"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryImpressions struct {
+	mutex   sync.Mutex
+	batches [][]impression.Impression
+	clicks  []impression.Click
+}
+
+func (m *memoryImpressions) SaveBatch(ctx context.Context, impressions []impression.Impression) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.batches = append(m.batches, append([]impression.Impression(nil), impressions...))
+	return nil
+}
+
+func (m *memoryImpressions) SaveClick(ctx context.Context, click impression.Click) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.clicks = append(m.clicks, click)
+	return nil
+}
+
+func TestImpressionTrackerFlushesOnStop(t *testing.T) {
+	storage := &memoryImpressions{}
+	tracker := impression.NewTracker(storage, logging.GetLogger(), 100, 3, time.Hour)
+	tracker.Start()
+
+	for i := 1; i <= 7; i++ {
+		tracker.Track(impression.Impression{BannerID: i, FeatureID: 1, TagID: 1, ShownAt: time.Now()})
+	}
+	tracker.Stop()
+
+	total := 0
+	for _, batch := range storage.batches {
+		if len(batch) > 3 {
+			t.Errorf("expected batches of at most 3 impressions, got %d", len(batch))
+		}
+		total += len(batch)
+	}
+	if total != 7 {
+		t.Errorf("expected 7 saved impressions, got %d", total)
+	}
+}
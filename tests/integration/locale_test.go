@@ -0,0 +1,34 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	supported := []string{"ru", "en"}
+	tests := []struct {
+		name     string
+		target   string
+		header   string
+		expected string
+	}{
+		{name: "default", target: "/user_banner", expected: "ru"},
+		{name: "quality order", target: "/user_banner", header: "de;q=0.9, en-US;q=0.8, ru;q=0.5", expected: "en"},
+		{name: "lang parameter wins", target: "/user_banner?lang=ru", header: "en", expected: "ru"},
+		{name: "excluded language", target: "/user_banner", header: "en;q=0, fr", expected: "ru"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Accept-Language", tt.header)
+			}
+			if locale := banner.NegotiateLocale(req, supported); locale != tt.expected {
+				t.Errorf("expected locale %q, got %q", tt.expected, locale)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// patchStorage хранит один баннер и применяет к нему изменения PATCH
+type patchStorage struct {
+	banner.Storage
+	banner banner.Banner
+}
+
+func (s *patchStorage) GetFeatureSchema(_ context.Context, _ int) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (s *patchStorage) PatchBanner(_ context.Context, id int, apply func(current *banner.Banner) error) (*banner.Banner, error) {
+	if id != s.banner.ID {
+		return nil, banner.ErrBannerNotFound
+	}
+	current := s.banner
+	if err := apply(&current); err != nil {
+		return nil, err
+	}
+	current.Version++
+	s.banner = current
+	return &current, nil
+}
+
+func newPatchRouter(t *testing.T, storage *patchStorage) *chi.Mux {
+	cache := banner.NewBannerCache(5 * time.Minute)
+	t.Cleanup(cache.Close)
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	router := chi.NewRouter()
+	router.Patch("/banner/{id}", bannerHandler.PatchBannerHandler)
+	return router
+}
+
+func patchBanner(router http.Handler, patch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("PATCH", "/banner/1", strings.NewReader(patch))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func testPatchBanner() banner.Banner {
+	startsAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	return banner.Banner{
+		ID:        1,
+		Title:     "Banner",
+		Text:      "Text",
+		URL:       "https://example.com",
+		Content:   json.RawMessage(`{"color":"#fff","cta":{"label":"Buy","url":"https://example.com/buy"},"images":["a.png","b.png"]}`),
+		IsActive:  true,
+		FeatureID: banner.Feature{ID: 1, Name: "Feature 1"},
+		Tags:      []banner.Tag{{ID: 1, Name: "Tag 1"}, {ID: 2, Name: "Tag 2"}},
+		StartsAt:  &startsAt,
+		Timezone:  "UTC",
+		Targeting: &banner.Targeting{Countries: []string{"RU"}},
+		Version:   1,
+	}
+}
+
+func TestPatchBannerMergePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		content string
+	}{
+		{
+			name:    "null deletes member",
+			patch:   `{"content": {"color": null}}`,
+			content: `{"cta":{"label":"Buy","url":"https://example.com/buy"},"images":["a.png","b.png"]}`,
+		},
+		{
+			name:    "nested objects are merged",
+			patch:   `{"content": {"cta": {"label": "Order", "style": {"bold": true}}}}`,
+			content: `{"color":"#fff","cta":{"label":"Order","style":{"bold":true},"url":"https://example.com/buy"},"images":["a.png","b.png"]}`,
+		},
+		{
+			name:    "arrays are replaced wholesale",
+			patch:   `{"content": {"images": ["c.png"]}}`,
+			content: `{"color":"#fff","cta":{"label":"Buy","url":"https://example.com/buy"},"images":["c.png"]}`,
+		},
+		{
+			name:    "non-object value replaces object",
+			patch:   `{"content": {"cta": "https://example.com/cta"}}`,
+			content: `{"color":"#fff","cta":"https://example.com/cta","images":["a.png","b.png"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &patchStorage{banner: testPatchBanner()}
+			w := patchBanner(newPatchRouter(t, storage), tt.patch)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if string(storage.banner.Content) != tt.content {
+				t.Errorf("expected content %s, got %s", tt.content, storage.banner.Content)
+			}
+		})
+	}
+
+	t.Run("null deletes optional field", func(t *testing.T) {
+		storage := &patchStorage{banner: testPatchBanner()}
+		w := patchBanner(newPatchRouter(t, storage), `{"targeting": null, "starts_at": null}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if storage.banner.Targeting != nil || storage.banner.StartsAt != nil {
+			t.Errorf("expected targeting and starts_at to be removed, got %+v", storage.banner)
+		}
+	})
+
+	for _, patch := range []string{`[{"op": "replace", "path": "/title", "value": "x"}]`, `"title"`, `null`, `{`} {
+		t.Run("non-object patch "+patch, func(t *testing.T) {
+			storage := &patchStorage{banner: testPatchBanner()}
+			w := patchBanner(newPatchRouter(t, storage), patch)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d; got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+			if !reflect.DeepEqual(storage.banner, testPatchBanner()) {
+				t.Errorf("rejected patch must not change the banner, got %+v", storage.banner)
+			}
+		})
+	}
+}
+
+func TestPatchBannerKeepsOtherFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  string
+		change func(b *banner.Banner)
+	}{
+		{
+			name:   "only is_active",
+			patch:  `{"is_active": false}`,
+			change: func(b *banner.Banner) { b.IsActive = false },
+		},
+		{
+			name:   "only tags",
+			patch:  `{"tags": [{"id": 3}]}`,
+			change: func(b *banner.Banner) { b.Tags = []banner.Tag{{ID: 3}} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &patchStorage{banner: testPatchBanner()}
+			w := patchBanner(newPatchRouter(t, storage), tt.patch)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			expected := testPatchBanner()
+			tt.change(&expected)
+			expected.Version = 2
+			if !reflect.DeepEqual(storage.banner, expected) {
+				t.Errorf("expected banner\n%+v\ngot\n%+v", expected, storage.banner)
+			}
+			if etag := w.Header().Get("ETag"); etag != `"2"` {
+				t.Errorf("expected ETag \"2\", got %q", etag)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/banner/dbbanner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// revisionStorage хранит ревизии одного баннера в памяти
+type revisionStorage struct {
+	banner.Storage
+	revisions []*banner.Revision
+}
+
+func (s *revisionStorage) GetBannerRevisions(_ context.Context, bannerID int) ([]*banner.Revision, error) {
+	if bannerID != 1 {
+		return nil, banner.ErrBannerNotFound
+	}
+	return s.revisions, nil
+}
+
+func (s *revisionStorage) RestoreBannerRevision(_ context.Context, bannerID, version int) (*banner.Revision, error) {
+	for _, rev := range s.revisions {
+		if rev.BannerID == bannerID && rev.Version == version {
+			restored := *rev
+			restored.Version = len(s.revisions) + 1
+			restored.RestoredFrom = &version
+			s.revisions = append([]*banner.Revision{&restored}, s.revisions...)
+			return &restored, nil
+		}
+	}
+	return nil, banner.ErrRevisionNotFound
+}
+
+func TestBannerVersionsListAndRestore(t *testing.T) {
+	startsAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	storage := &revisionStorage{revisions: []*banner.Revision{
+		{BannerID: 1, Version: 2, Title: "New", FeatureID: 1, TagIDs: []int{2}, Timezone: "UTC"},
+		{BannerID: 1, Version: 1, Title: "Old", FeatureID: 1, TagIDs: []int{1}, StartsAt: &startsAt, Timezone: "Europe/Moscow"},
+	}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	router := chi.NewRouter()
+	router.Get("/banner/{id}/versions", bannerHandler.GetBannerVersions)
+	router.Post("/banner/{id}/versions/{version}/restore", bannerHandler.RestoreBannerVersion)
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	w := serve("POST", "/banner/1/versions/1/restore")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = serve("GET", "/banner/1/versions")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var revisions []banner.Revision
+	if err := json.Unmarshal(w.Body.Bytes(), &revisions); err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(revisions))
+	}
+	latest := revisions[0]
+	if latest.Version != 3 || latest.RestoredFrom == nil || *latest.RestoredFrom != 1 || latest.Title != "Old" ||
+		latest.StartsAt == nil || !latest.StartsAt.Equal(startsAt) || latest.Timezone != "Europe/Moscow" {
+		t.Errorf("unexpected restored revision %+v", latest)
+	}
+
+	if w := serve("POST", "/banner/1/versions/7/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown version; got %d", http.StatusNotFound, w.Code)
+	}
+	if w := serve("GET", "/banner/2/versions"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown banner; got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRestoreBannerRevisionRestoresFullState(t *testing.T) {
+	testDB := openTestDB(t)
+	repository := dbbanner.NewBannerRepository(testDB, logging.GetLogger())
+	ctx := context.TODO()
+
+	// Пары (5, 1) и (5, 2) не заняты баннерами из тестовых данных
+	startsAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(24 * time.Hour)
+	bn := &banner.Banner{
+		Title:     fmt.Sprintf("Revision test %d", time.Now().UnixNano()),
+		Text:      "Old text",
+		URL:       "https://example.com/old",
+		IsActive:  true,
+		FeatureID: banner.Feature{ID: 5},
+		Tags:      []banner.Tag{{ID: 1}},
+		StartsAt:  &startsAt,
+		EndsAt:    &endsAt,
+		Timezone:  "Europe/Moscow",
+	}
+	if err := repository.CreateBanner(ctx, bn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM banner_tags WHERE banner_id = $1`, bn.ID)
+		testDB.Exec(ctx, `DELETE FROM banners WHERE id = $1`, bn.ID)
+	})
+
+	updated := *bn
+	updated.Text = "New text"
+	updated.Tags = []banner.Tag{{ID: 2}}
+	updated.StartsAt, updated.EndsAt, updated.Timezone = nil, nil, "UTC"
+	updated.Version = 0
+	if err := repository.UpdateBanner(ctx, &updated); err != nil {
+		t.Fatal(err)
+	}
+
+	rev, err := repository.RestoreBannerRevision(ctx, bn.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.Version != 3 {
+		t.Errorf("expected restore to create version 3, got %d", rev.Version)
+	}
+
+	// Последняя ревизия снова на паре (5, 1) с прежним содержимым и расписанием
+	candidates, err := repository.GetBannerCandidates(ctx, 1, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored *banner.Banner
+	for _, candidate := range candidates {
+		if candidate.ID == bn.ID {
+			restored = candidate
+		}
+	}
+	if restored == nil {
+		t.Fatalf("expected banner %d on pair (5, 1) after restore", bn.ID)
+	}
+	if restored.Text != "Old text" || restored.Timezone != "Europe/Moscow" || !reflect.DeepEqual(restored.Tags, []banner.Tag{{ID: 1, Name: "Tag 1"}}) {
+		t.Errorf("unexpected restored banner %+v", restored)
+	}
+	if restored.StartsAt == nil || !restored.StartsAt.Equal(startsAt) || restored.EndsAt == nil || !restored.EndsAt.Equal(endsAt) {
+		t.Errorf("expected schedule %v - %v, got %v - %v", startsAt, endsAt, restored.StartsAt, restored.EndsAt)
+	}
+}
+
+func TestPublishedRevisionIsolatedFromDraft(t *testing.T) {
+	testDB := openTestDB(t)
+	repository := dbbanner.NewBannerRepository(testDB, logging.GetLogger())
+	ctx := context.TODO()
+	publish := func(id int) {
+		t.Helper()
+		_, err := repository.ChangeStatus(ctx, id, 0, func(state *banner.WorkflowState) error {
+			state.Status = banner.StatusPublished
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bn := &banner.Banner{
+		Title:     fmt.Sprintf("Publish test %d", time.Now().UnixNano()),
+		Text:      "Text",
+		URL:       "https://example.com",
+		IsActive:  true,
+		FeatureID: banner.Feature{ID: 5},
+		Tags:      []banner.Tag{{ID: 1}},
+		Timezone:  "UTC",
+	}
+	if err := repository.CreateBanner(ctx, bn); err != nil {
+		t.Fatal(err)
+	}
+	other := &banner.Banner{Title: bn.Title + " other", Text: "Text", URL: "https://example.com", FeatureID: banner.Feature{ID: 5}, Tags: []banner.Tag{{ID: 1}}, Timezone: "UTC"}
+	t.Cleanup(func() {
+		for _, id := range []int{bn.ID, other.ID} {
+			testDB.Exec(ctx, `DELETE FROM banner_tags WHERE banner_id = $1`, id)
+			testDB.Exec(ctx, `DELETE FROM banners WHERE id = $1`, id)
+		}
+	})
+	publish(bn.ID)
+
+	// Черновик переезжает на пару (5, 2) с расписанием, вариантом и переводом
+	startsAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	updated := *bn
+	updated.Tags = []banner.Tag{{ID: 2}}
+	updated.StartsAt = &startsAt
+	updated.Version = 0
+	if err := repository.UpdateBanner(ctx, &updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.CreateVariant(ctx, &banner.Variant{BannerID: bn.ID, Name: "B", Title: "Variant B", Weight: 50}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.SaveTranslation(ctx, &banner.Translation{BannerID: bn.ID, Locale: "en", Title: "Banner"}); err != nil {
+		t.Fatal(err)
+	}
+
+	published, err := repository.GetPublishedBanner(ctx, bn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published.Version != 1 || published.StartsAt != nil || len(published.Variants) != 0 || len(published.Translations) != 0 ||
+		!reflect.DeepEqual(published.Tags, []banner.Tag{{ID: 1, Name: "Tag 1"}}) {
+		t.Errorf("expected users to get version 1 without draft changes, got %+v", published)
+	}
+	draft, err := repository.GetBannerCandidates(ctx, 2, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(draft) != 1 || draft[0].Version != 4 || len(draft[0].Variants) != 1 || len(draft[0].Translations) != 1 {
+		t.Errorf("expected draft version 4 with variant and translation, got %+v", draft)
+	}
+
+	// Пара (5, 1) занята опубликованной ревизией, хотя черновик ее уже не использует
+	var conflict *banner.ConflictError
+	if err = repository.CreateBanner(ctx, other); !errors.As(err, &conflict) || !reflect.DeepEqual(conflict.BannerIDs, []int{bn.ID}) {
+		t.Fatalf("expected conflict with published revision of banner %d, got %v", bn.ID, err)
+	}
+
+	publish(bn.ID)
+	published, err = repository.GetPublishedBanner(ctx, bn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published.Version != 4 || published.StartsAt == nil || !published.StartsAt.Equal(startsAt) ||
+		len(published.Variants) != 1 || published.Variants[0].Title != "Variant B" ||
+		len(published.Translations) != 1 || published.Translations[0].Locale != "en" {
+		t.Errorf("expected published version 4 with draft changes, got %+v", published)
+	}
+	if err = repository.CreateBanner(ctx, other); err != nil {
+		t.Errorf("expected pair (5, 1) to be free after publication, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/banner/dbbanner"
+	"banner-service/pkg/logging"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetBannersSearchParameters(t *testing.T) {
+	tests := []struct {
+		query     string
+		sort      string
+		desc      bool
+		highlight bool
+	}{
+		// Результаты поиска по умолчанию упорядочены по убыванию релевантности
+		{query: "q=sale", sort: "relevance", desc: true},
+		{query: "q=sale&highlight=true", sort: "relevance", desc: true, highlight: true},
+		{query: "q=sale&sort=-created_at", sort: "created_at", desc: true},
+		{query: "q=++sale++&sort=title", sort: "title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			storage := &pageStorage{banner: &banner.Banner{ID: 7, Title: "Banner"}}
+			bannerHandler := banner.NewHandler(storage, logging.GetLogger(), nil)
+
+			w := httptest.NewRecorder()
+			bannerHandler.GetBanners(w, httptest.NewRequest("GET", "/banner?"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			filter := storage.filter
+			if filter.Query != "sale" || filter.Sort != tt.sort || filter.Desc != tt.desc || filter.Highlight != tt.highlight {
+				t.Errorf("unexpected filter %+v", *filter)
+			}
+		})
+	}
+
+	bannerHandler := banner.NewHandler(&pageStorage{}, logging.GetLogger(), nil)
+	w := httptest.NewRecorder()
+	bannerHandler.GetBanners(w, httptest.NewRequest("GET", "/banner?sort=relevance", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for relevance sort without q; got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// searchWord возвращает слово из букв, которого нет в тестовых данных
+func searchWord() string {
+	return "zq" + strings.Map(func(r rune) rune { return 'a' + (r - '0') }, strconv.FormatInt(time.Now().UnixNano(), 10))
+}
+
+func TestGetBannersSearchDB(t *testing.T) {
+	testDB := openTestDB(t)
+	repository := dbbanner.NewBannerRepository(testDB, logging.GetLogger())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	word := searchWord()
+	// Опечатка в середине слова: такой баннер находит только триграммный поиск
+	typo := word[:10] + "x" + word[11:]
+	create := func(title, text string) *banner.Banner {
+		t.Helper()
+		// Баннеры с таргетингом могут делить пару (5, 1) друг с другом
+		bn := &banner.Banner{
+			Title:     title,
+			Text:      text,
+			URL:       "https://example.com",
+			FeatureID: banner.Feature{ID: 5},
+			Tags:      []banner.Tag{{ID: 1}},
+			Timezone:  "UTC",
+			Targeting: &banner.Targeting{Countries: []string{"ZZ"}},
+		}
+		if err := repository.CreateBanner(ctx, bn); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			testDB.Exec(context.Background(), `DELETE FROM banner_tags WHERE banner_id = $1`, bn.ID)
+			testDB.Exec(context.Background(), `DELETE FROM banners WHERE id = $1`, bn.ID)
+		})
+		return bn
+	}
+	inTitle := create("Spring "+word+" sale", "Discounts for everyone")
+	inText := create("Spring offer", "Discounts on "+word+" items")
+	misspelled := create("Autumn offer "+word[:3], "Only "+typo+" today")
+	_ = create("Winter offer "+word[:3], "Nothing related")
+
+	ids := func(page *banner.Page) []int {
+		result := []int{}
+		for _, bn := range page.Banners {
+			result = append(result, bn.ID)
+		}
+		return result
+	}
+
+	// Совпадение в заголовке весит больше, чем в тексте, опечатка - меньше точного совпадения
+	filter := banner.Filter{Query: word, Sort: "relevance", Desc: true, Limit: 20, Highlight: true}
+	page, err := repository.GetBannersByFiltering(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{inTitle.ID, inText.ID, misspelled.ID}; !reflect.DeepEqual(ids(page), expected) || page.Total != 3 {
+		t.Fatalf("expected banners %v ranked by relevance, got %v of %d", expected, ids(page), page.Total)
+	}
+	for i := 1; i < len(page.Banners); i++ {
+		if page.Banners[i].Rank == nil || *page.Banners[i].Rank > *page.Banners[i-1].Rank {
+			t.Errorf("expected non-increasing rank, got %v after %v", page.Banners[i].Rank, page.Banners[i-1].Rank)
+		}
+	}
+
+	marked := "<b>" + word + "</b>"
+	if hl := page.Banners[0].Highlight; hl == nil || !strings.Contains(hl.Title, marked) {
+		t.Errorf("expected %s in title highlight, got %+v", marked, hl)
+	}
+	if hl := page.Banners[1].Highlight; hl == nil || !strings.Contains(hl.Text, marked) || strings.Contains(hl.Title, "<b>") {
+		t.Errorf("expected %s only in text highlight, got %+v", marked, hl)
+	}
+
+	// Без highlight фрагменты не возвращаются
+	filter.Highlight = false
+	page, err = repository.GetBannersByFiltering(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Banners[0].Highlight != nil {
+		t.Errorf("expected no highlight without highlight parameter, got %+v", page.Banners[0].Highlight)
+	}
+
+	// Курсор по релевантности продолжает выдачу с того же места
+	filter.Limit = 2
+	page, err = repository.GetBannersByFiltering(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids(page), []int{inTitle.ID, inText.ID}) || page.NextCursor == nil {
+		t.Fatalf("expected first page %v with cursor, got %v", []int{inTitle.ID, inText.ID}, ids(page))
+	}
+	filter.After, err = banner.DecodeCursor(page.NextCursor.Encode(), "relevance", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err = repository.GetBannersByFiltering(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids(page), []int{misspelled.ID}) || page.NextCursor != nil {
+		t.Errorf("expected last page %v without cursor, got %v", []int{misspelled.ID}, ids(page))
+	}
+	if page.Total != 3 {
+		t.Errorf("expected total of all matches, got %d", page.Total)
+	}
+}
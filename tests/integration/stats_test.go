@@ -0,0 +1,199 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/impression"
+	"banner-service/internal/models/impression/dbimpression"
+	"banner-service/internal/models/stats"
+	"banner-service/internal/models/stats/dbstats"
+	"banner-service/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reportStorage запоминает фильтр запроса статистики
+type reportStorage struct {
+	stats.Storage
+	filter *stats.Filter
+	err    error
+}
+
+func (s *reportStorage) GetReport(_ context.Context, filter stats.Filter) (*stats.Report, error) {
+	s.filter = &filter
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &stats.Report{From: filter.From, To: filter.To, Granularity: filter.Granularity, Series: []stats.Point{}}, nil
+}
+
+func newStatsRouter(storage stats.Storage) *chi.Mux {
+	statsHandler := stats.NewHandler(storage, logging.GetLogger())
+	router := chi.NewRouter()
+	router.Get("/stats", statsHandler.GetStats)
+	router.Get("/banner/{id}/stats", statsHandler.GetBannerStats)
+	return router
+}
+
+func TestGetStatsParameters(t *testing.T) {
+	from := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		target string
+		filter stats.Filter
+	}{
+		{
+			name:   "banner stats",
+			target: "/banner/3/stats?from=2024-04-01&to=2024-04-15",
+			filter: stats.Filter{BannerID: intPtr(3), From: from, To: from.AddDate(0, 0, 14), Granularity: stats.GranularityDay},
+		},
+		{
+			name:   "filters",
+			target: "/stats?feature_id=1&tag_id=2&variant_id=0&from=2024-04-01&to=2024-04-02&granularity=hour",
+			filter: stats.Filter{FeatureID: intPtr(1), TagID: intPtr(2), VariantID: intPtr(0), From: from, To: from.AddDate(0, 0, 1), Granularity: stats.GranularityHour},
+		},
+		{
+			// Границы периода выравниваются по часам наружу
+			name:   "hour alignment",
+			target: "/stats?from=2024-04-01T10:30:00Z&to=2024-04-01T15:10:00%2B03:00",
+			filter: stats.Filter{From: from.Add(10 * time.Hour), To: from.Add(13 * time.Hour), Granularity: stats.GranularityDay},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &reportStorage{}
+			w := httptest.NewRecorder()
+			newStatsRouter(storage).ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got := *storage.filter; !reflect.DeepEqual(got, tt.filter) {
+				t.Errorf("expected filter %+v, got %+v", tt.filter, got)
+			}
+		})
+	}
+
+	// По умолчанию отдается статистика за последнюю неделю по дням
+	storage := &reportStorage{}
+	w := httptest.NewRecorder()
+	newStatsRouter(storage).ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if filter := storage.filter; filter.Granularity != stats.GranularityDay || filter.To.Sub(filter.From) != 7*24*time.Hour+time.Hour {
+		t.Errorf("expected last week by day, got %+v", *filter)
+	}
+
+	for _, target := range []string{
+		"/banner/x/stats",
+		"/stats?feature_id=a",
+		"/stats?tag_id=1.5",
+		"/stats?variant_id=x",
+		"/stats?from=yesterday",
+		"/stats?to=2024-13-01",
+		"/stats?from=2024-04-02&to=2024-04-01",
+		"/stats?granularity=week",
+		"/stats?from=2020-01-01&to=2024-01-01&granularity=hour",
+	} {
+		t.Run("invalid "+target, func(t *testing.T) {
+			storage := &reportStorage{}
+			w := httptest.NewRecorder()
+			newStatsRouter(storage).ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d; got %d", http.StatusBadRequest, w.Code)
+			}
+			if storage.filter != nil {
+				t.Error("storage must not be queried for invalid parameters")
+			}
+		})
+	}
+}
+
+func TestGetStatsErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{err: fmt.Errorf("stats report: %w", context.DeadlineExceeded), status: http.StatusRequestTimeout},
+		{err: errors.New("pq: connection to 10.0.0.5 reset"), status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		newStatsRouter(&reportStorage{err: tt.err}).ServeHTTP(w, httptest.NewRequest("GET", "/banner/1/stats", nil))
+		if w.Code != tt.status {
+			t.Errorf("expected status %d for %v; got %d", tt.status, tt.err, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "10.0.0.5") {
+			t.Errorf("internal error must not be exposed, got %q", w.Body.String())
+		}
+	}
+}
+
+func TestStatsRollupDB(t *testing.T) {
+	testDB := openTestDB(t)
+	logger := logging.GetLogger()
+	repository := dbstats.NewStatsRepository(testDB, logger)
+	events := dbimpression.NewImpressionRepository(testDB, logger)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Отдельный баннер, чтобы не пересекаться с другими событиями в базе
+	bannerID := int(time.Now().UnixNano()%1000000) + 1000000
+	t.Cleanup(func() {
+		for _, table := range []string{"impressions", "clicks", "banner_stats_hourly", "banner_stats_hourly_users"} {
+			testDB.Exec(context.Background(), `DELETE FROM `+table+` WHERE banner_id = $1`, bannerID)
+		}
+	})
+
+	until := time.Now().UTC().Truncate(time.Hour)
+	hour := until.Add(-2 * time.Hour)
+	show := func(userID int) {
+		t.Helper()
+		err := events.SaveBatch(ctx, []impression.Impression{{BannerID: bannerID, FeatureID: 5, TagID: 1, UserID: &userID, ShownAt: hour.Add(10 * time.Minute)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := func() stats.Counters {
+		t.Helper()
+		report, err := repository.GetReport(ctx, stats.Filter{BannerID: &bannerID, From: hour, To: hour.Add(time.Hour), Granularity: stats.GranularityHour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report.Totals
+	}
+
+	show(1)
+	show(2)
+	userID := 1
+	if err := events.SaveClick(ctx, impression.Click{BannerID: bannerID, FeatureID: 5, TagID: 1, UserID: &userID, ClickedAt: hour.Add(15 * time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	watermark, err := repository.Rollup(ctx, until.Add(-6*time.Hour), until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watermark.Before(until) {
+		t.Errorf("expected watermark at least %s, got %s", until, watermark)
+	}
+	if totals := report(); totals.Impressions != 2 || totals.Clicks != 1 || totals.UniqueUsers != 2 {
+		t.Fatalf("expected 2 impressions, 1 click and 2 users, got %+v", totals)
+	}
+
+	// Событие за уже агрегированный час, записанное с опозданием, учитывается при следующем пересчете,
+	// а повторный пересчет не удваивает учтенные события
+	show(3)
+	if _, err = repository.Rollup(ctx, until.Add(-6*time.Hour), until); err != nil {
+		t.Fatal(err)
+	}
+	if totals := report(); totals.Impressions != 3 || totals.Clicks != 1 || totals.UniqueUsers != 3 {
+		t.Errorf("expected late impression to be counted once, got %+v", totals)
+	}
+}
@@ -0,0 +1,30 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSelectBannerFallsBackToUntargeted(t *testing.T) {
+	fallback := &banner.Banner{ID: 1, IsActive: true}
+	mobileRU := &banner.Banner{ID: 2, IsActive: true, Targeting: &banner.Targeting{
+		Countries:  []string{"RU"},
+		Platforms:  []string{"ios", "android"},
+		AppVersion: &banner.VersionRange{Min: "2.0"},
+	}}
+	candidates := []*banner.Banner{fallback, mobileRU}
+	always := func(*banner.Banner) bool { return true }
+
+	req := httptest.NewRequest("GET", "/user_banner?tag_id=1&feature_id=1&country=ru", nil)
+	req.Header.Set("X-Platform", "iOS")
+	req.Header.Set("X-App-Version", "2.1.0")
+	if selected := banner.SelectBanner(candidates, banner.AudienceFromRequest(req), always); selected != mobileRU {
+		t.Errorf("expected targeted banner to be selected, got %+v", selected)
+	}
+
+	req.Header.Set("X-App-Version", "1.9")
+	if selected := banner.SelectBanner(candidates, banner.AudienceFromRequest(req), always); selected != fallback {
+		t.Errorf("expected fallback banner for old app version, got %+v", selected)
+	}
+}
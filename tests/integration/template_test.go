@@ -0,0 +1,97 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// templateStorage отдает одну версию шаблона и запоминает созданный из нее баннер
+type templateStorage struct {
+	banner.Storage
+	banner.TemplateStorage
+	template banner.Template
+	created  *banner.Banner
+}
+
+func (s *templateStorage) GetTemplate(_ context.Context, id, version int) (*banner.Template, error) {
+	if id != s.template.ID || (version != 0 && version != s.template.Version) {
+		return nil, banner.ErrTemplateNotFound
+	}
+	template := s.template
+	return &template, nil
+}
+
+func (s *templateStorage) GetFeatureSchema(_ context.Context, _ int) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (s *templateStorage) CreateBanner(_ context.Context, bn *banner.Banner) error {
+	bn.ID, bn.Version = 1, 1
+	s.created = bn
+	return nil
+}
+
+func TestCreateBannerFromTemplate(t *testing.T) {
+	days := "7"
+	storage := &templateStorage{template: banner.Template{
+		ID:      1,
+		Title:   "Скидка {{percent}}%",
+		Text:    "Осталось {{ days }} дней",
+		URL:     "https://example.com/sale",
+		Content: json.RawMessage(`{"badge": "-{{percent}}%", "priority": 1}`),
+		Variables: []banner.TemplateVariable{
+			{Name: "percent"},
+			{Name: "days", Default: &days},
+		},
+		Version: 3,
+	}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+	bannerHandler.Templates = storage
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/banner", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		bannerHandler.CreateBannerHandler(w, req)
+		return w
+	}
+
+	w := create(`{"template_id": 1, "variables": {"percent": "20"}, "feature_id": {"id": 1}, "tags": [{"id": 2}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	created := storage.created
+	if created.Title != "Скидка 20%" || created.Text != "Осталось 7 дней" || created.URL != "https://example.com/sale" {
+		t.Errorf("unexpected rendered banner %+v", created)
+	}
+	if string(created.Content) != `{"badge":"-20%","priority":1}` {
+		t.Errorf("unexpected rendered content %s", created.Content)
+	}
+	if created.TemplateVersion == nil || *created.TemplateVersion != 3 {
+		t.Errorf("expected template version 3, got %v", created.TemplateVersion)
+	}
+
+	// Без обязательной переменной и с необъявленной баннер не создается
+	w = create(`{"template_id": 1, "variables": {"discount": "20"}, "feature_id": {"id": 1}, "tags": [{"id": 2}]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d; got %d", http.StatusBadRequest, w.Code)
+	}
+	for _, field := range []string{"variables.percent", "variables.discount"} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("expected error for %s, got %s", field, w.Body.String())
+		}
+	}
+
+	w = create(`{"template_id": 1, "template_version": 2, "variables": {"percent": "20"}, "feature_id": {"id": 1}, "tags": [{"id": 2}]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for missing template version; got %d", http.StatusBadRequest, w.Code)
+	}
+}
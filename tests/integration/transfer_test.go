@@ -0,0 +1,312 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/banner/dbbanner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// transferStorage хранит баннеры в памяти и, как база данных, записывает импорт,
+// только если он фиксируется и ни одна строка не завершилась ошибкой
+type transferStorage struct {
+	banner.Storage
+	banners []*banner.Banner
+	// conflictTitle - заголовок баннера, пара которого занята баннером 99
+	conflictTitle string
+	commits       int
+}
+
+func (s *transferStorage) ExportBanners(_ context.Context, fn func(*banner.Banner) error) error {
+	for _, bn := range s.banners {
+		if err := fn(bn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *transferStorage) GetFeatureAndTagIDs(_ context.Context) (map[string]int, map[string]int, error) {
+	return map[string]int{"Feature 1": 1, "Feature 2": 2}, map[string]int{"Tag 1": 1, "Tag 2": 2, "Tag 3": 3}, nil
+}
+
+func (s *transferStorage) GetFeatureSchema(_ context.Context, _ int) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (s *transferStorage) ImportBanners(_ context.Context, items []*banner.ImportItem, commit bool) error {
+	imported := append([]*banner.Banner(nil), s.banners...)
+	failed := false
+	for _, item := range items {
+		if len(item.Errors) > 0 {
+			failed = true
+			continue
+		}
+		if item.Title == s.conflictTitle {
+			item.ConflictIDs = []int{99}
+			item.Errors = append(item.Errors, banner.FieldError{Field: "tags", Message: "banner conflicts with banner 99"})
+			failed = true
+			continue
+		}
+
+		bn := *item.Banner
+		bn.ID = len(imported) + 1
+		item.Action = banner.ImportCreate
+		for i, existing := range imported {
+			if existing.Title == bn.Title {
+				bn.ID = existing.ID
+				imported[i] = &bn
+				item.Action = banner.ImportUpdate
+			}
+		}
+		if item.Action == banner.ImportCreate {
+			imported = append(imported, &bn)
+		}
+		item.BannerID = bn.ID
+	}
+
+	if commit && !failed {
+		s.banners = imported
+		s.commits++
+	}
+	return nil
+}
+
+func exportBanners(t *testing.T, bannerHandler *banner.Handler, format string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	bannerHandler.ExportBanners(w, httptest.NewRequest("GET", "/banners/export?format="+format, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	return w.Body.String()
+}
+
+func importBanners(t *testing.T, bannerHandler *banner.Handler, query, contentType, body string) (int, banner.ImportReport) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/banners/import"+query, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	bannerHandler.ImportBanners(w, req)
+
+	var report banner.ImportReport
+	if w.Code == http.StatusOK || w.Code == http.StatusUnprocessableEntity {
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("unexpected import response %s: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, report
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	startsAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	endsAt := startsAt.AddDate(0, 1, 0)
+	source := []*banner.Banner{
+		{
+			ID: 1, Title: "Spring sale", Text: "Text, with \"quotes\"", URL: "https://example.com/sale", IsActive: true,
+			Content:   json.RawMessage(`{"discount":20}`),
+			FeatureID: banner.Feature{ID: 1, Name: "Feature 1"},
+			Tags:      []banner.Tag{{ID: 1, Name: "Tag 1"}, {ID: 2, Name: "Tag 2"}},
+			StartsAt:  &startsAt, EndsAt: &endsAt, Timezone: "UTC",
+			Targeting:    &banner.Targeting{Countries: []string{"RU"}, Platforms: []string{"ios"}},
+			FrequencyCap: &banner.FrequencyCap{Count: 3, Window: banner.Duration{Duration: 24 * time.Hour}},
+		},
+		{
+			ID: 2, Title: "Plain", Text: "Text", URL: "https://example.com", Timezone: "UTC",
+			FeatureID: banner.Feature{ID: 2, Name: "Feature 2"},
+			Tags:      []banner.Tag{{ID: 3, Name: "Tag 3"}},
+		},
+	}
+
+	for _, tt := range []struct{ format, contentType string }{{"json", ""}, {"csv", "text/csv"}} {
+		t.Run(tt.format, func(t *testing.T) {
+			exported := exportBanners(t, banner.NewHandler(&transferStorage{banners: source}, logging.GetLogger(), nil), tt.format)
+
+			target := &transferStorage{}
+			cache := banner.NewBannerCache(5 * time.Minute)
+			defer cache.Close()
+			targetHandler := banner.NewHandler(target, logging.GetLogger(), cache)
+			status, report := importBanners(t, targetHandler, "", tt.contentType, exported)
+			if status != http.StatusOK || !report.Committed || report.Created != 2 || report.Failed != 0 {
+				t.Fatalf("expected both banners created, got %d %+v", status, report)
+			}
+
+			// Выгрузка импортированных баннеров совпадает с исходной
+			if again := exportBanners(t, targetHandler, tt.format); again != exported {
+				t.Errorf("round trip changed export:\n%s\nvs\n%s", exported, again)
+			}
+
+			// Повторный импорт обновляет баннеры с теми же заголовками
+			status, report = importBanners(t, targetHandler, "", tt.contentType, exported)
+			if status != http.StatusOK || report.Updated != 2 || report.Created != 0 || len(target.banners) != 2 {
+				t.Errorf("expected both banners updated, got %d %+v with %d banners", status, report, len(target.banners))
+			}
+		})
+	}
+}
+
+func TestImportBannersDryRun(t *testing.T) {
+	storage := &transferStorage{banners: []*banner.Banner{{ID: 1, Title: "Existing", FeatureID: banner.Feature{ID: 1, Name: "Feature 1"}}}}
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), nil)
+
+	body := `[
+		{"title": "Existing", "text": "New text", "url": "https://example.com", "feature": "Feature 1", "tags": ["Tag 1"]},
+		{"title": "New", "text": "Text", "url": "https://example.com", "feature": "Feature 2", "tags": ["Tag 2"]}
+	]`
+	status, report := importBanners(t, bannerHandler, "?dry_run=true", "", body)
+	if status != http.StatusOK || !report.DryRun || report.Committed || report.Updated != 1 || report.Created != 1 {
+		t.Fatalf("expected dry run report with one update and one create, got %d %+v", status, report)
+	}
+	if actions := []string{report.Rows[0].Action, report.Rows[1].Action}; !reflect.DeepEqual(actions, []string{banner.ImportUpdate, banner.ImportCreate}) {
+		t.Errorf("unexpected row actions %v", actions)
+	}
+	if storage.commits != 0 || len(storage.banners) != 1 || storage.banners[0].Text != "" {
+		t.Errorf("dry run must not change banners, got %d commits and %+v", storage.commits, storage.banners)
+	}
+}
+
+func TestImportBannersRowErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		// fields - поля с ошибками по строкам импорта, nil - строка без ошибок
+		fields [][]string
+	}{
+		{
+			name: "json",
+			body: `[
+				{"title": "Valid", "text": "Text", "url": "https://example.com", "feature": "Feature 1", "tags": ["Tag 1"]},
+				{"title": "Unknown", "text": "Text", "url": "https://example.com", "feature": "Feature 9", "tags": ["Tag 9"]},
+				{"title": "Valid", "text": "Text", "url": "https://example.com", "feature": "Feature 2", "tags": ["Tag 2"]},
+				{"title": "Broken", "is_active": "yes"},
+				{"title": "Window", "text": "Text", "url": "https://example.com", "feature": "Feature 1", "tags": ["Tag 3"],
+				 "starts_at": "2024-04-02T00:00:00Z", "ends_at": "2024-04-01T00:00:00Z"}
+			]`,
+			fields: [][]string{nil, {"feature", "tags"}, {"title"}, {"body"}, {"ends_at"}},
+		},
+		{
+			name:        "csv",
+			contentType: "text/csv",
+			body: "title,text,url,is_active,feature,tags,content\n" +
+				"Valid,Text,https://example.com,true,Feature 1,Tag 1|Tag 2,\n" +
+				"Flag,Text,https://example.com,maybe,Feature 1,Tag 3,\n" +
+				"Content,Text,https://example.com,true,Feature 2,Tag 3,{broken\n",
+			fields: [][]string{nil, {"is_active"}, {"content"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &transferStorage{}
+			bannerHandler := banner.NewHandler(storage, logging.GetLogger(), nil)
+
+			status, report := importBanners(t, bannerHandler, "", tt.contentType, tt.body)
+			if status != http.StatusUnprocessableEntity || report.Committed || report.Failed != len(tt.fields)-1 {
+				t.Fatalf("expected 422 with %d failed rows, got %d %+v", len(tt.fields)-1, status, report)
+			}
+			for i, row := range report.Rows {
+				var fields []string
+				for _, fieldErr := range row.Errors {
+					fields = append(fields, fieldErr.Field)
+				}
+				if row.Row != i+1 || !reflect.DeepEqual(fields, tt.fields[i]) {
+					t.Errorf("row %d: expected errors in %v, got %+v", i+1, tt.fields[i], row.Errors)
+				}
+			}
+			if storage.commits != 0 || len(storage.banners) != 0 {
+				t.Errorf("import with errors must not write banners, got %+v", storage.banners)
+			}
+		})
+	}
+
+	// Конфликт пары (фича, тег) попадает в отчет вместе с баннерами, с которыми он возник
+	storage := &transferStorage{conflictTitle: "Taken"}
+	body := `[{"title": "Taken", "text": "Text", "url": "https://example.com", "feature": "Feature 1", "tags": ["Tag 1"]}]`
+	status, report := importBanners(t, banner.NewHandler(storage, logging.GetLogger(), nil), "", "", body)
+	if status != http.StatusUnprocessableEntity || !reflect.DeepEqual(report.Rows[0].ConflictIDs, []int{99}) {
+		t.Errorf("expected conflict with banner 99, got %d %+v", status, report.Rows)
+	}
+
+	for _, body := range []string{`{"title": "Not an array"}`, `[]`} {
+		if status, _ := importBanners(t, banner.NewHandler(&transferStorage{}, logging.GetLogger(), nil), "", "", body); status != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d; got %d", body, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestImportBannersDB(t *testing.T) {
+	testDB := openTestDB(t)
+	repository := dbbanner.NewBannerRepository(testDB, logging.GetLogger())
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(repository, logging.GetLogger(), cache)
+
+	title := fmt.Sprintf("Import test %d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		testDB.Exec(context.Background(), `DELETE FROM banner_tags WHERE banner_id IN (SELECT id FROM banners WHERE title LIKE $1)`, title+"%")
+		testDB.Exec(context.Background(), `DELETE FROM banners WHERE title LIKE $1`, title+"%")
+	})
+	count := func() int {
+		t.Helper()
+		var n int
+		if err := testDB.QueryRow(context.Background(), `SELECT COUNT(*) FROM banners WHERE title LIKE $1`, title+"%").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	valid := fmt.Sprintf(`{"title": %q, "text": "Text", "url": "https://example.com", "feature": "Feature 5", "tags": ["Tag 2"]}`, title)
+	// Пара (Feature 1, Tag 1) занята баннером 1 из тестовых данных
+	conflicting := fmt.Sprintf(`{"title": %q, "text": "Text", "url": "https://example.com", "feature": "Feature 1", "tags": ["Tag 1"]}`, title+" conflict")
+
+	status, report := importBanners(t, bannerHandler, "", "", "["+valid+","+conflicting+"]")
+	if status != http.StatusUnprocessableEntity || len(report.Rows[1].ConflictIDs) == 0 {
+		t.Fatalf("expected conflict of second row, got %d %+v", status, report.Rows)
+	}
+	if report.Rows[0].BannerID != 0 || count() != 0 {
+		t.Fatalf("failed import must be rolled back, got %d banners", count())
+	}
+
+	status, report = importBanners(t, bannerHandler, "?dry_run=true", "", "["+valid+"]")
+	if status != http.StatusOK || report.Created != 1 || count() != 0 {
+		t.Fatalf("expected dry run without changes, got %d %+v and %d banners", status, report, count())
+	}
+
+	status, report = importBanners(t, bannerHandler, "", "", "["+valid+"]")
+	if status != http.StatusOK || !report.Committed || report.Rows[0].BannerID == 0 || count() != 1 {
+		t.Fatalf("expected banner to be created, got %d %+v", status, report)
+	}
+
+	// Созданный баннер выгружается с именами фичи и тегов и загружается обратно как обновление
+	var exported []banner.TransferBanner
+	if err := json.Unmarshal([]byte(exportBanners(t, bannerHandler, "json")), &exported); err != nil {
+		t.Fatal(err)
+	}
+	var found []banner.TransferBanner
+	for _, bn := range exported {
+		if bn.Title == title {
+			found = append(found, bn)
+		}
+	}
+	if len(found) != 1 || found[0].Feature != "Feature 5" || !reflect.DeepEqual(found[0].Tags, []string{"Tag 2"}) {
+		t.Fatalf("expected imported banner in export, got %+v", found)
+	}
+	data, err := json.Marshal(found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, report = importBanners(t, bannerHandler, "", "", string(data))
+	if status != http.StatusOK || report.Updated != 1 || count() != 1 {
+		t.Errorf("expected exported banner to be updated in place, got %d %+v", status, report)
+	}
+}
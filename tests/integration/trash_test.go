@@ -0,0 +1,333 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/banner/dbbanner"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// trashStorage хранит баннеры и корзину в памяти
+type trashStorage struct {
+	banner.Storage
+	mutex   sync.Mutex
+	banners map[int]*banner.Banner
+	// purgeBefore - граница, переданная последней очистке корзины
+	purgeBefore time.Time
+}
+
+func (s *trashStorage) DeleteBanner(_ context.Context, id, _ int) error {
+	bn, ok := s.banners[id]
+	if !ok || bn.DeletedAt != nil {
+		return banner.ErrBannerNotFound
+	}
+	now := time.Now()
+	bn.DeletedAt = &now
+	return nil
+}
+
+func (s *trashStorage) GetBannersByFiltering(_ context.Context, filter banner.Filter) (*banner.Page, error) {
+	page := &banner.Page{}
+	for id := 1; id <= len(s.banners); id++ {
+		if bn := s.banners[id]; (bn.DeletedAt != nil) == filter.Deleted {
+			page.Banners = append(page.Banners, bn)
+		}
+	}
+	page.Total = len(page.Banners)
+	return page, nil
+}
+
+func (s *trashStorage) RestoreBanner(_ context.Context, id int) error {
+	bn, ok := s.banners[id]
+	if !ok || bn.DeletedAt == nil {
+		return banner.ErrBannerNotFound
+	}
+	bn.DeletedAt = nil
+	return nil
+}
+
+func (s *trashStorage) GetPublishedBanner(_ context.Context, id int) (*banner.Banner, error) {
+	bn, ok := s.banners[id]
+	if !ok || bn.DeletedAt != nil {
+		return nil, banner.ErrBannerNotFound
+	}
+	return bn, nil
+}
+
+func (s *trashStorage) PurgeDeletedBanners(_ context.Context, before time.Time, limit int) ([]int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.purgeBefore = before
+	var ids []int
+	for id, bn := range s.banners {
+		if bn.DeletedAt != nil && bn.DeletedAt.Before(before) && len(ids) < limit {
+			delete(s.banners, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func TestTrashDeleteListRestore(t *testing.T) {
+	storage := &trashStorage{banners: map[int]*banner.Banner{
+		1: {ID: 1, Title: "Banner 1", FeatureID: banner.Feature{ID: 1}, Tags: []banner.Tag{{ID: 1}, {ID: 2}}},
+		2: {ID: 2, Title: "Banner 2", FeatureID: banner.Feature{ID: 2}, Tags: []banner.Tag{{ID: 1}}},
+	}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	router := chi.NewRouter()
+	router.Get("/banner", bannerHandler.GetBanners)
+	router.Get("/banner/trash", bannerHandler.GetTrash)
+	router.Delete("/delete-banner/{id}", bannerHandler.DeleteBannerHandler)
+	router.Post("/banner/{id}/restore", bannerHandler.RestoreBannerHandler)
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+	listed := func(target string) []int {
+		w := serve("GET", target)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d; got %d: %s", target, http.StatusOK, w.Code, w.Body.String())
+		}
+		var response struct {
+			Items []banner.Banner `json:"items"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, bn := range response.Items {
+			ids = append(ids, bn.ID)
+		}
+		return ids
+	}
+
+	cache.SetBanners(context.TODO(), "1-1-ru", []*banner.Banner{storage.banners[1]})
+	if w := serve("DELETE", "/delete-banner/1"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if _, ok := cache.GetBanners(context.TODO(), "1-1-ru"); ok {
+		t.Error("expected banner moved to trash to be evicted from cache")
+	}
+	if ids := listed("/banner"); !reflect.DeepEqual(ids, []int{2}) {
+		t.Errorf("expected banner 2 outside trash, got %v", ids)
+	}
+	if ids := listed("/banner/trash"); !reflect.DeepEqual(ids, []int{1}) {
+		t.Errorf("expected banner 1 in trash, got %v", ids)
+	}
+
+	// Пока баннер был в корзине, пары (1, 1) и (1, 2) закэшированы без него
+	cache.SetBanners(context.TODO(), "1-1-ru", nil)
+	cache.SetBanners(context.TODO(), "2-1-en", nil)
+	cache.SetBanners(context.TODO(), "1-2-ru", []*banner.Banner{storage.banners[2]})
+
+	if w := serve("POST", "/banner/1/restore"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	for _, key := range []string{"1-1-ru", "2-1-en"} {
+		if _, ok := cache.GetBanners(context.TODO(), key); ok {
+			t.Errorf("expected candidates %s of restored banner to be evicted from cache", key)
+		}
+	}
+	if _, ok := cache.GetBanners(context.TODO(), "1-2-ru"); !ok {
+		t.Error("expected candidates of other pairs to stay in cache")
+	}
+	if ids := listed("/banner/trash"); len(ids) != 0 {
+		t.Errorf("expected empty trash after restore, got %v", ids)
+	}
+
+	if w := serve("POST", "/banner/1/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for banner outside trash; got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestPurgerPurgesExpiredTrash(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	recent := time.Now()
+	storage := &trashStorage{banners: map[int]*banner.Banner{
+		1: {ID: 1, DeletedAt: &old},
+		2: {ID: 2, DeletedAt: &old},
+		3: {ID: 3, DeletedAt: &recent},
+		4: {ID: 4},
+	}}
+
+	purger := banner.NewPurger(storage, logging.GetLogger(), time.Hour, time.Hour)
+	purger.Start()
+	defer purger.Stop()
+
+	// Первая очистка запускается сразу после старта
+	remaining := func() int {
+		storage.mutex.Lock()
+		defer storage.mutex.Unlock()
+		return len(storage.banners)
+	}
+	for deadline := time.Now().Add(time.Second); remaining() > 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if len(storage.banners) != 2 || storage.banners[3] == nil || storage.banners[4] == nil {
+		t.Errorf("expected only banners deleted before retention to be purged, got %v", storage.banners)
+	}
+	if since := time.Since(storage.purgeBefore); since < time.Hour || since > time.Hour+time.Minute {
+		t.Errorf("expected purge boundary one hour ago, got %v", storage.purgeBefore)
+	}
+}
+
+func TestDeleteTagCascade(t *testing.T) {
+	testDB := openTestDB(t)
+	logger := logging.GetLogger()
+	repository := dbbanner.NewBannerRepository(testDB, logger)
+	tags := dbbanner.NewTagRepository(testDB, logger)
+	ctx := context.TODO()
+
+	tag := &banner.Tag{Name: fmt.Sprintf("Cascade test %d", time.Now().UnixNano())}
+	if err := tags.CreateTag(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM tags WHERE id = $1`, tag.ID)
+	})
+	create := func() *banner.Banner {
+		t.Helper()
+		bn := &banner.Banner{
+			Title:     fmt.Sprintf("Cascade test %d", time.Now().UnixNano()),
+			Text:      "Text",
+			URL:       "https://example.com",
+			FeatureID: banner.Feature{ID: 5},
+			Tags:      []banner.Tag{{ID: tag.ID}},
+			Timezone:  "UTC",
+		}
+		if err := repository.CreateBanner(ctx, bn); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			testDB.Exec(ctx, `DELETE FROM banner_tags WHERE banner_id = $1`, bn.ID)
+			testDB.Exec(ctx, `DELETE FROM banners WHERE id = $1`, bn.ID)
+		})
+		return bn
+	}
+	// Пара (фича, тег) освобождается, когда баннер переносится в корзину
+	trashed := create()
+	if err := repository.DeleteBanner(ctx, trashed.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	live := create()
+
+	var inUse *banner.InUseError
+	if _, err := tags.DeleteTag(ctx, tag.ID, false); !errors.As(err, &inUse) || !reflect.DeepEqual(inUse.BannerIDs, []int{trashed.ID, live.ID}) {
+		t.Fatalf("expected InUseError with banners %v without cascade, got %v", []int{trashed.ID, live.ID}, err)
+	}
+
+	// Каскад удаляет и баннер из корзины, и действующий баннер
+	deleted, err := tags.DeleteTag(ctx, tag.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deleted, []int{trashed.ID, live.ID}) {
+		t.Errorf("expected banners %v purged with tag, got %v", []int{trashed.ID, live.ID}, deleted)
+	}
+	for _, id := range deleted {
+		if _, err = repository.GetBannerVersion(ctx, id); !errors.Is(err, banner.ErrBannerNotFound) {
+			t.Errorf("expected purged banner %d to be gone, got %v", id, err)
+		}
+		if err = repository.RestoreBanner(ctx, id); !errors.Is(err, banner.ErrBannerNotFound) {
+			t.Errorf("expected purged banner %d not to be in trash, got %v", id, err)
+		}
+	}
+	if _, err = tags.GetTag(ctx, tag.ID); !errors.Is(err, banner.ErrTagNotFound) {
+		t.Errorf("expected tag to be deleted, got %v", err)
+	}
+}
+
+func TestDeleteTagUsedByPublishedRevision(t *testing.T) {
+	testDB := openTestDB(t)
+	logger := logging.GetLogger()
+	repository := dbbanner.NewBannerRepository(testDB, logger)
+	tags := dbbanner.NewTagRepository(testDB, logger)
+	ctx := context.TODO()
+
+	published, draft := &banner.Tag{Name: fmt.Sprintf("Published tag %d", time.Now().UnixNano())}, &banner.Tag{Name: fmt.Sprintf("Draft tag %d", time.Now().UnixNano())}
+	for _, tag := range []*banner.Tag{published, draft} {
+		if err := tags.CreateTag(ctx, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bn := &banner.Banner{
+		Title:     fmt.Sprintf("Revision tag test %d", time.Now().UnixNano()),
+		Text:      "Text",
+		URL:       "https://example.com",
+		IsActive:  true,
+		FeatureID: banner.Feature{ID: 5},
+		Tags:      []banner.Tag{{ID: published.ID}},
+		Timezone:  "UTC",
+	}
+	if err := repository.CreateBanner(ctx, bn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM banner_tags WHERE banner_id = $1`, bn.ID)
+		testDB.Exec(ctx, `DELETE FROM banners WHERE id = $1`, bn.ID)
+		testDB.Exec(ctx, `DELETE FROM tags WHERE id = ANY($1)`, []int{published.ID, draft.ID})
+	})
+	publish := func() {
+		t.Helper()
+		_, err := repository.ChangeStatus(ctx, bn.ID, 0, func(state *banner.WorkflowState) error {
+			state.Status = banner.StatusPublished
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	publish()
+
+	// Черновик уже использует другой тег, но опубликованная ревизия еще показывает баннер по старому
+	updated := *bn
+	updated.Tags = []banner.Tag{{ID: draft.ID}}
+	updated.Version = 0
+	if err := repository.UpdateBanner(ctx, &updated); err != nil {
+		t.Fatal(err)
+	}
+	var inUse *banner.InUseError
+	if _, err := tags.DeleteTag(ctx, published.ID, false); !errors.As(err, &inUse) || !reflect.DeepEqual(inUse.BannerIDs, []int{bn.ID}) {
+		t.Fatalf("expected tag of published revision to be in use by banner %d, got %v", bn.ID, err)
+	}
+
+	// Ревизии, записанные до проверки, могут ссылаться на удаленные тег и фичу: баннер все равно отдается
+	_, err := testDB.Exec(ctx, `UPDATE banner_revisions SET tag_ids = tag_ids || -1 WHERE banner_id = $1 AND version = 1`, bn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shown, err := repository.GetPublishedBanner(ctx, bn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(shown.Tags, []banner.Tag{{ID: published.ID, Name: published.Name}}) {
+		t.Errorf("expected missing tag to be skipped, got %+v", shown.Tags)
+	}
+
+	// После публикации черновика старый тег можно удалить, но откатиться к ревизии с ним уже нельзя
+	publish()
+	if _, err = tags.DeleteTag(ctx, published.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	var validationErr *banner.ValidationError
+	if _, err = repository.RestoreBannerRevision(ctx, bn.ID, 1); !errors.As(err, &validationErr) || len(validationErr.Fields) != 2 {
+		t.Errorf("expected validation error for both deleted tags of revision 1, got %v", err)
+	}
+}
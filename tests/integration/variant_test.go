@@ -0,0 +1,100 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/pkg/logging"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUserBannerVariants(t *testing.T) {
+	storage := &candidateStorage{candidates: []*banner.Banner{{
+		ID: 1, Title: "Banner", Text: "Text", URL: "https://example.com", IsActive: true, Version: 1,
+		Variants: []banner.Variant{
+			{ID: 10, BannerID: 1, Name: "A", Weight: 70},
+			{ID: 11, BannerID: 1, Name: "B", Title: "Variant B", Weight: 30},
+		},
+	}}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	variantOf := func(userID int) *banner.Banner {
+		req := httptest.NewRequest("GET", "/user_banner?tag_id=1&feature_id=1", nil)
+		if userID != 0 {
+			req = withUser(req, userID)
+		}
+		w := httptest.NewRecorder()
+		bannerHandler.GetUserBanner(w, req, &fakeAdminChecker{})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var shown banner.Banner
+		if err := json.Unmarshal(w.Body.Bytes(), &shown); err != nil {
+			t.Fatal(err)
+		}
+		return &shown
+	}
+
+	if shown := variantOf(0); shown.VariantID != nil {
+		t.Errorf("anonymous user must get the banner without variant, got variant %d", *shown.VariantID)
+	}
+
+	const users = 2000
+	counts := map[int]int{}
+	for userID := 1; userID <= users; userID++ {
+		shown := variantOf(userID)
+		if shown.VariantID == nil {
+			t.Fatalf("expected variant for user %d", userID)
+		}
+		if *shown.VariantID == 11 && shown.Title != "Variant B" {
+			t.Errorf("expected title of variant B, got %q", shown.Title)
+		}
+		// Повторный запрос того же пользователя попадает в тот же вариант
+		if again := variantOf(userID); again.VariantID == nil || *again.VariantID != *shown.VariantID {
+			t.Fatalf("user %d moved from variant %d to %v", userID, *shown.VariantID, again.VariantID)
+		}
+		counts[*shown.VariantID]++
+	}
+
+	share := float64(counts[10]) / users
+	if math.Abs(share-0.7) > 0.05 {
+		t.Errorf("expected about 70%% of users in variant A, got %.1f%% (%v)", share*100, counts)
+	}
+}
+
+func TestUserBannerVariantTranslated(t *testing.T) {
+	storage := &candidateStorage{candidates: []*banner.Banner{{
+		ID: 1, Title: "Баннер", Text: "Текст", URL: "https://example.com", IsActive: true, Version: 1,
+		Variants: []banner.Variant{
+			{ID: 10, BannerID: 1, Name: "B", Title: "Вариант B", URL: "https://example.com/b", Weight: 100},
+		},
+		Translations: []banner.Translation{{BannerID: 1, Locale: "en", Title: "Banner", Text: "Text"}},
+	}}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+	bannerHandler.Locales = []string{"ru", "en"}
+
+	req := withUser(httptest.NewRequest("GET", "/user_banner?tag_id=1&feature_id=1&lang=en", nil), 5)
+	w := httptest.NewRecorder()
+	bannerHandler.GetUserBanner(w, req, &fakeAdminChecker{})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var shown banner.Banner
+	if err := json.Unmarshal(w.Body.Bytes(), &shown); err != nil {
+		t.Fatal(err)
+	}
+	if shown.VariantID == nil || *shown.VariantID != 10 || shown.URL != "https://example.com/b" {
+		t.Errorf("expected variant URL, got %+v", shown)
+	}
+	// Заголовок варианта на основном языке не должен заменять перевод
+	if shown.Title != "Banner" || shown.Text != "Text" || shown.Locale != "en" {
+		t.Errorf("expected translation over variant, got %q / %q in %q", shown.Title, shown.Text, shown.Locale)
+	}
+}
@@ -0,0 +1,113 @@
+package banner_test
+
+import (
+	"banner-service/internal/models/banner"
+	"banner-service/internal/models/user"
+	"banner-service/pkg/logging"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// workflowStorage хранит состояние согласования одного баннера в памяти
+type workflowStorage struct {
+	banner.Storage
+	state banner.WorkflowState
+	// published - опубликованная версия баннера, nil - баннер не опубликован
+	published *banner.Banner
+}
+
+func (s *workflowStorage) GetPublishedBanner(_ context.Context, _ int) (*banner.Banner, error) {
+	if s.published == nil {
+		return nil, banner.ErrBannerNotFound
+	}
+	return s.published, nil
+}
+
+func (s *workflowStorage) ChangeStatus(_ context.Context, _, _ int, apply func(state *banner.WorkflowState) error) (*banner.WorkflowState, error) {
+	state := s.state
+	if err := apply(&state); err != nil {
+		return nil, err
+	}
+	s.state = state
+	return &state, nil
+}
+
+func TestBannerReviewTransitions(t *testing.T) {
+	authorID := 1
+	storage := &workflowStorage{state: banner.WorkflowState{BannerID: 1, Version: 2, Status: banner.StatusInReview, AuthorID: &authorID}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	router := chi.NewRouter()
+	router.Post("/banner/{id}/approve", bannerHandler.Transition("approve"))
+	router.Post("/banner/{id}/publish", bannerHandler.Transition("publish"))
+	transition := func(name string, actorID int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/banner/1/"+name, nil)
+		req = req.WithContext(user.NewContext(req.Context(), &user.CustomClaims{ID: actorID, IsAdmin: true}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Опубликовать можно только одобренную версию
+	if w := transition("publish", 2); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d for publish from review; got %d", http.StatusConflict, w.Code)
+	}
+	if w := transition("approve", authorID); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for approval by author; got %d", http.StatusForbidden, w.Code)
+	}
+
+	w := transition("approve", 2)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var state banner.WorkflowState
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != banner.StatusApproved || state.ReviewedBy == nil || *state.ReviewedBy != 2 {
+		t.Errorf("expected banner approved by user 2, got %+v", state)
+	}
+}
+
+func TestPublishEvictsPublishedPlacements(t *testing.T) {
+	storage := &workflowStorage{state: banner.WorkflowState{BannerID: 1, Version: 3, Status: banner.StatusApproved}}
+	cache := banner.NewBannerCache(5 * time.Minute)
+	defer cache.Close()
+	bannerHandler := banner.NewHandler(storage, logging.GetLogger(), cache)
+
+	router := chi.NewRouter()
+	router.Post("/banner/{id}/publish", bannerHandler.Transition("publish"))
+
+	// Версия 3 переезжает с пары (1, 1) на (1, 2), где закэшированы кандидаты без нее
+	old := &banner.Banner{ID: 1, Version: 1, FeatureID: banner.Feature{ID: 1}, Tags: []banner.Tag{{ID: 1}}}
+	other := &banner.Banner{ID: 2, Version: 1, FeatureID: banner.Feature{ID: 2}, Tags: []banner.Tag{{ID: 1}}}
+	cache.SetBanners(context.TODO(), "1-1-ru", []*banner.Banner{old})
+	cache.SetBanners(context.TODO(), "2-1-ru", nil)
+	cache.SetBanners(context.TODO(), "2-1-en", nil)
+	cache.SetBanners(context.TODO(), "1-2-ru", []*banner.Banner{other})
+	storage.published = &banner.Banner{ID: 1, Version: 3, FeatureID: banner.Feature{ID: 1}, Tags: []banner.Tag{{ID: 2}}}
+
+	req := httptest.NewRequest("POST", "/banner/1/publish", nil)
+	req = req.WithContext(user.NewContext(req.Context(), &user.CustomClaims{ID: 2, IsAdmin: true}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	for _, key := range []string{"1-1-ru", "2-1-ru", "2-1-en"} {
+		if _, ok := cache.GetBanners(context.TODO(), key); ok {
+			t.Errorf("expected candidates %s to be evicted after publication", key)
+		}
+	}
+	if _, ok := cache.GetBanners(context.TODO(), "1-2-ru"); !ok {
+		t.Error("expected candidates of other pairs to stay in cache")
+	}
+}